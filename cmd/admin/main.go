@@ -0,0 +1,234 @@
+// Команда admin — административные операции сервиса, не доступные через HTTP API.
+//
+// Использование:
+//
+//	admin apikey create -name <имя>
+//	admin apikey list
+//	admin apikey revoke -id <id>
+//	admin migrate up
+//	admin migrate down [-steps N]
+//	admin migrate status
+//	admin domain disable -domain <домен>
+//
+// Конфигурация загружается так же, как у API (CONFIG_PATH и переменные окружения).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"tinyurl/internal/app"
+	"tinyurl/internal/config"
+	"tinyurl/internal/db"
+	"tinyurl/internal/repository"
+	"tinyurl/internal/service"
+)
+
+const usage = `Использование:
+  admin apikey create -name <имя>   выпустить ключ API
+  admin apikey list                 список ключей
+  admin apikey revoke -id <id>      отозвать ключ
+  admin migrate up                  применить недостающие миграции схемы
+  admin migrate down [-steps N]     откатить N последних миграций (по умолчанию 1)
+  admin migrate status              список миграций и время применения
+  admin domain disable -domain <d>  отключить все ссылки на домен и его поддомены
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "ошибка:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) < 2 || (args[0] != "apikey" && args[0] != "migrate" && args[0] != "domain") {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	if cfg.Storage.Driver == config.StorageMemory {
+		return errors.New("хранилище memory живёт только внутри процесса API, административные команды к нему неприменимы")
+	}
+
+	if args[0] == "migrate" {
+		return runMigrate(cfg, args[1], args[2:])
+	}
+
+	storage, database, err := app.OpenStorage(cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := database.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	ctx := context.Background()
+	if args[0] == "domain" {
+		return runDomain(ctx, cfg, storage, args[1], args[2:])
+	}
+
+	keys := service.NewAPIKeyService(storage.APIKeys)
+
+	switch args[1] {
+	case "create":
+		return createKey(ctx, keys, args[2:])
+	case "list":
+		return listKeys(ctx, keys)
+	case "revoke":
+		return revokeKey(ctx, keys, args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+func createKey(ctx context.Context, keys *service.APIKeyService, args []string) error {
+	fs := flag.NewFlagSet("apikey create", flag.ExitOnError)
+	name := fs.String("name", "", "имя владельца ключа (записывается в историю изменений)")
+	_ = fs.Parse(args)
+
+	plain, key, err := keys.Create(ctx, *name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("ключ #%d (%s) создан. Сохраните его — повторно он не показывается:\n%s\n", key.ID, key.Name, plain)
+	return nil
+}
+
+func listKeys(ctx context.Context, keys *service.APIKeyService) error {
+	list, err := keys.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tИМЯ\tПРЕФИКС\tСОЗДАН\tОТОЗВАН")
+	for _, k := range list {
+		revoked := "-"
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s…\t%s\t%s\n", k.ID, k.Name, k.Prefix, k.CreatedAt.Format(time.RFC3339), revoked)
+	}
+	return tw.Flush()
+}
+
+func revokeKey(ctx context.Context, keys *service.APIKeyService, args []string) error {
+	fs := flag.NewFlagSet("apikey revoke", flag.ExitOnError)
+	id := fs.Int64("id", 0, "ID ключа")
+	_ = fs.Parse(args)
+
+	if err := keys.Revoke(ctx, *id); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			return fmt.Errorf("ключ #%d не найден или уже отозван", *id)
+		}
+		return err
+	}
+
+	fmt.Printf("ключ #%d отозван\n", *id)
+	return nil
+}
+
+func runDomain(ctx context.Context, cfg *config.Config, storage *repository.Storage, command string, args []string) error {
+	if command != "disable" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("domain disable", flag.ExitOnError)
+	domain := fs.String("domain", "", "домен; ссылки на его поддомены тоже отключаются")
+	_ = fs.Parse(args)
+
+	// Кэш нужен, чтобы сбросить записи в общем Redis: удаление оттуда же
+	// рассылается репликам API, и они очищают свои локальные кэши. Без Redis
+	// до локальных кэшей запущенных реплик команда не дотягивается
+	urlCache, closeCache := app.NewURLCache(cfg)
+	defer closeCache()
+	svc := service.NewURLService(storage.URLs, nil, cfg.App.BaseURL, cfg.App.DefaultRedirectType, urlCache, service.NopMetrics{})
+
+	codes, err := svc.DisableDomain(ctx, *domain)
+	for _, code := range codes {
+		fmt.Println(code)
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDomain) {
+			return errors.New("укажите домен: -domain example.com")
+		}
+		return err
+	}
+
+	fmt.Printf("отключено ссылок: %d\n", len(codes))
+	if len(codes) > 0 && !cfg.Redis.Enabled() && cfg.Cache.Size > 0 {
+		fmt.Fprintf(os.Stderr,
+			"внимание: redis не настроен — запущенные реплики API продолжат отдавать редиректы этих ссылок "+
+				"из локального кэша до %s (cache.ttl); чтобы отключение подействовало сразу, перезапустите их\n",
+			cfg.Cache.TTL)
+	}
+	return nil
+}
+
+func runMigrate(cfg *config.Config, command string, args []string) error {
+	database, dialect, err := app.OpenDB(cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := database.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	runner, err := db.Migrator(database, dialect)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := runner.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("применено миграций: %d\n", applied)
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "сколько последних миграций откатить")
+		_ = fs.Parse(args)
+		if *steps < 1 {
+			return errors.New("steps должен быть положительным")
+		}
+
+		reverted, err := runner.Down(ctx, *steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("откачено миграций: %d\n", reverted)
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ВЕРСИЯ\tИМЯ\tПРИМЕНЕНА")
+		for _, s := range statuses {
+			applied := "-"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%03d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	return nil
+}
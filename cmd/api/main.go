@@ -13,6 +13,11 @@ import (
 // @description Сервис сокращения ссылок.
 // @host        localhost:8080
 // @BasePath    /
+//
+// @securityDefinitions.apikey BearerAuth
+// @in                         header
+// @name                       Authorization
+// @description                Ключ API в формате "Bearer tu_…"
 func main() {
 	a, err := app.Init()
 	if err != nil {
@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"tinyurl/internal/repository"
+)
+
+// defaultBatchSize — размер пачки, если в конфигурации он не задан.
+const defaultBatchSize = 1000
+
+// Repository — операции хранилища, необходимые для агрегации.
+type Repository interface {
+	AggregatePending(ctx context.Context, limit int, summarize repository.Summarizer) (int, error)
+}
+
+// Aggregator — фоновый обработчик, периодически сворачивающий новые события
+// переходов в почасовые и подневные агрегаты, из которых строится статистика.
+type Aggregator struct {
+	repo      Repository
+	interval  time.Duration
+	batchSize int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAggregator создаёт обработчик агрегации с заданным интервалом и размером пачки.
+// Нулевой интервал отключает периодическую агрегацию.
+func NewAggregator(repo Repository, interval time.Duration, batchSize int) *Aggregator {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return &Aggregator{
+		repo:      repo,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Start запускает периодическую агрегацию в отдельной горутине.
+func (a *Aggregator) Start() {
+	if a.interval <= 0 {
+		slog.Info("агрегация переходов отключена")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.RunOnce(ctx)
+			}
+		}
+	}()
+
+	slog.Info("агрегация переходов запущена", "interval", a.interval.String(), "batch_size", a.batchSize)
+}
+
+// Stop останавливает агрегацию и дожидается завершения текущего прохода.
+func (a *Aggregator) Stop() {
+	if a.cancel == nil {
+		return
+	}
+	a.cancel()
+	a.wg.Wait()
+}
+
+// RunOnce агрегирует накопившиеся события пачками, пока они не закончатся,
+// и возвращает общее число обработанных событий.
+func (a *Aggregator) RunOnce(ctx context.Context) int {
+	var total int
+	for ctx.Err() == nil {
+		n, err := a.repo.AggregatePending(ctx, a.batchSize, Summarize)
+		if err != nil {
+			slog.Error("ошибка агрегации переходов", "error", err, "processed", total)
+			return total
+		}
+		total += n
+		if n < a.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.Info("переходы агрегированы", "processed", total)
+	}
+	return total
+}
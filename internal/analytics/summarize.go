@@ -0,0 +1,112 @@
+package analytics
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"tinyurl/internal/model"
+	"tinyurl/pkg/useragent"
+)
+
+const (
+	// directReferrer — значение источника для переходов без заголовка Referer.
+	directReferrer = "direct"
+	// maxValueLength — ограничение колонки click_dimensions.value.
+	maxValueLength = 255
+)
+
+type hourKey struct {
+	urlID int64
+	hour  time.Time
+}
+
+type dimensionKey struct {
+	urlID     int64
+	day       time.Time
+	dimension string
+	value     string
+}
+
+// Summarize сворачивает события переходов в приращения почасовых агрегатов
+// и подневных разбивок по источнику, браузеру, ОС, классу устройства, стране
+// и варианту A/B-теста (страна и вариант — только для переходов, где они известны).
+// Границы часов и дней считаются в UTC.
+func Summarize(clicks []model.Click) ([]model.ClickHourly, []model.ClickDimension) {
+	hours := make(map[hourKey]int64)
+	dims := make(map[dimensionKey]int64)
+
+	for _, c := range clicks {
+		at := c.ClickedAt.UTC()
+		hours[hourKey{c.URLID, at.Truncate(time.Hour)}]++
+
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		ua := useragent.Parse(c.UserAgent)
+		for dimension, value := range map[string]string{
+			model.DimensionReferrer: referrerHost(c.Referrer),
+			model.DimensionBrowser:  ua.Browser,
+			model.DimensionOS:       ua.OS,
+			model.DimensionDevice:   ua.Device,
+		} {
+			dims[dimensionKey{c.URLID, day, dimension, value}]++
+		}
+		if c.Country != "" {
+			dims[dimensionKey{c.URLID, day, model.DimensionCountry, c.Country}]++
+		}
+		if c.Variant != "" {
+			dims[dimensionKey{c.URLID, day, model.DimensionVariant, c.Variant}]++
+		}
+	}
+
+	hourly := make([]model.ClickHourly, 0, len(hours))
+	for k, n := range hours {
+		hourly = append(hourly, model.ClickHourly{URLID: k.urlID, Hour: k.hour, Clicks: n})
+	}
+
+	dimensions := make([]model.ClickDimension, 0, len(dims))
+	for k, n := range dims {
+		dimensions = append(dimensions, model.ClickDimension{
+			URLID:     k.urlID,
+			Day:       k.day,
+			Dimension: k.dimension,
+			Value:     k.value,
+			Clicks:    n,
+		})
+	}
+
+	// Стабильный порядок строк снижает риск взаимных блокировок,
+	// когда несколько реплик обновляют одни и те же агрегаты.
+	slices.SortFunc(hourly, func(a, b model.ClickHourly) int {
+		return cmp.Or(cmp.Compare(a.URLID, b.URLID), a.Hour.Compare(b.Hour))
+	})
+	slices.SortFunc(dimensions, func(a, b model.ClickDimension) int {
+		return cmp.Or(
+			cmp.Compare(a.URLID, b.URLID),
+			a.Day.Compare(b.Day),
+			cmp.Compare(a.Dimension, b.Dimension),
+			cmp.Compare(a.Value, b.Value),
+		)
+	})
+
+	return hourly, dimensions
+}
+
+// referrerHost возвращает хост источника перехода без префикса www.
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return directReferrer
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Host == "" {
+		return useragent.Unknown
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if len(host) > maxValueLength {
+		host = host[:maxValueLength]
+	}
+	return host
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -10,18 +11,44 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
+	"tinyurl/internal/analytics"
+	"tinyurl/internal/blocklist"
+	"tinyurl/internal/cache"
+	"tinyurl/internal/clicks"
 	"tinyurl/internal/config"
 	"tinyurl/internal/db"
+	"tinyurl/internal/geoip"
+	"tinyurl/internal/handler"
+	"tinyurl/internal/janitor"
+	"tinyurl/internal/metrics"
+	"tinyurl/internal/migrate"
+	"tinyurl/internal/repository"
 	"tinyurl/internal/router"
+	"tinyurl/internal/service"
+	"tinyurl/internal/tracing"
 )
 
-// Application — основная структура приложения, содержащая конфигурацию, БД и HTTP-сервер.
+// Application — основная структура приложения, содержащая конфигурацию, БД, HTTP-сервер
+// и фоновые обработчики.
 type Application struct {
-	cfg    *config.Config
-	db     *gorm.DB
-	server *http.Server
+	cfg        *config.Config
+	db         *gorm.DB
+	closeCache func()
+	server     *http.Server
+	// metricsServer отдаёт /metrics на отдельном порту (app.metrics_port).
+	metricsServer *http.Server
+	janitor       *janitor.Janitor
+	clicks        *clicks.Recorder
+	aggregator    *analytics.Aggregator
+	// geoip — база GeoIP или nil, если она не настроена.
+	geoip *geoip.DB
+	// blocklist — список блокировок доменов или nil, если он не настроен.
+	blocklist *blocklist.List
+	// shutdownTracing дописывает накопленные спаны при остановке.
+	shutdownTracing func(context.Context) error
 }
 
 // Init инициализирует приложение: загружает конфигурацию, подключается к БД, создаёт HTTP-сервер.
@@ -35,28 +62,270 @@ func Init() (*Application, error) {
 		"snowflake_node", cfg.App.SnowflakeNode,
 	)
 
-	database, err := db.Init(cfg.Postgres.DSN())
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка инициализации базы данных: %w", err)
+		return nil, err
+	}
+	slog.Info("трассировка настроена", "exporter", cfg.Tracing.Exporter)
+
+	storage, database, err := OpenStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("хранилище готово", "driver", cfg.Storage.Driver)
+
+	// Без соли хеш IPv4-адреса восстанавливается перебором всех 2^32 значений
+	if cfg.Clicks.IPSalt == "" {
+		return nil, errors.New("не задана соль для хеширования IP клиентов: укажите clicks.ip_salt или CLICKS_IP_SALT")
+	}
+	recorder := clicks.NewRecorder(storage.Clicks, clicks.Config{
+		QueueSize:     cfg.Clicks.QueueSize,
+		BatchSize:     cfg.Clicks.BatchSize,
+		FlushInterval: cfg.Clicks.FlushInterval,
+		IPSalt:        cfg.Clicks.IPSalt,
+	})
+
+	urlCache, closeCache := NewURLCache(cfg)
+
+	m := metrics.New()
+	m.RegisterCache(urlCache.Stats)
+	m.RegisterClicksDropped(recorder.Dropped)
+	if database != nil {
+		if err := m.InstrumentDB(database); err != nil {
+			return nil, fmt.Errorf("ошибка подключения метрик БД: %w", err)
+		}
+		if err := tracing.InstrumentDB(database, dbSystem(cfg.Storage.Driver)); err != nil {
+			return nil, fmt.Errorf("ошибка подключения трассировки БД: %w", err)
+		}
+	}
+
+	var geo handler.CountryResolver = geoip.Nop{}
+	geoDB, err := openGeoIP(cfg.GeoIP)
+	if err != nil {
+		return nil, err
+	}
+	if geoDB != nil {
+		geo = geoDB
+	}
+
+	var domains service.DomainPolicy
+	blocked, err := openBlocklist(cfg.Blocklist)
+	if err != nil {
+		return nil, err
+	}
+	if blocked != nil {
+		domains = blocked
 	}
-	slog.Info("база данных готова")
 
 	app := &Application{
-		cfg: cfg,
-		db:  database,
+		cfg:             cfg,
+		db:              database,
+		closeCache:      closeCache,
+		shutdownTracing: shutdownTracing,
 		server: &http.Server{
 			Addr:    ":" + cfg.App.Port,
-			Handler: router.New(cfg, database),
+			Handler: router.New(cfg, storage, recorder, urlCache, m, geo, domains),
 		},
+		metricsServer: newMetricsServer(cfg.App.MetricsPort, m),
+		janitor: janitor.New(
+			storage.URLs,
+			cfg.App.JanitorInterval,
+			cfg.App.JanitorBatchSize,
+		),
+		clicks:    recorder,
+		geoip:     geoDB,
+		blocklist: blocked,
+		aggregator: analytics.NewAggregator(
+			storage.Stats,
+			cfg.Clicks.AggregateInterval,
+			cfg.Clicks.AggregateBatchSize,
+		),
 	}
 
 	return app, nil
 }
 
-// Run запускает HTTP-сервер и ожидает сигнала завершения.
+// OpenStorage открывает хранилище по storage.driver и, если не задан
+// storage.skip_migrations, применяет миграции схемы. Для postgres и sqlite возвращает
+// также подключение GORM (его нужно закрыть при остановке), для memory — nil.
+func OpenStorage(cfg *config.Config) (*repository.Storage, *gorm.DB, error) {
+	if cfg.Storage.Driver == config.StorageMemory {
+		slog.Warn("используется хранилище в памяти: данные будут потеряны при перезапуске")
+		return repository.NewMemoryStorage(), nil, nil
+	}
+
+	database, dialect, err := OpenDB(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !cfg.Storage.SkipMigrations {
+		if err := migrateUp(database, dialect); err != nil {
+			if sqlDB, dbErr := database.DB(); dbErr == nil {
+				sqlDB.Close()
+			}
+			return nil, nil, err
+		}
+	}
+
+	return repository.NewGormStorage(database), database, nil
+}
+
+// OpenDB подключается к базе postgres или sqlite без применения миграций
+// и возвращает диалект для migrate.
+func OpenDB(cfg *config.Config) (*gorm.DB, migrate.Dialect, error) {
+	var (
+		database *gorm.DB
+		dialect  migrate.Dialect
+		err      error
+	)
+	switch cfg.Storage.Driver {
+	case config.StoragePostgres:
+		database, err = db.Init(cfg.Postgres.DSN())
+		dialect = migrate.Postgres
+	case config.StorageSQLite:
+		database, err = db.InitSQLite(cfg.SQLite.Path)
+		dialect = migrate.SQLite
+	default:
+		return nil, "", fmt.Errorf("неизвестный драйвер хранилища %q", cfg.Storage.Driver)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("ошибка инициализации базы данных: %w", err)
+	}
+	return database, dialect, nil
+}
+
+// migrateUp применяет недостающие миграции. Реплики PostgreSQL, стартующие
+// одновременно, дожидаются друг друга на блокировке.
+func migrateUp(database *gorm.DB, dialect migrate.Dialect) error {
+	runner, err := db.Migrator(database, dialect)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	applied, err := runner.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка миграции схемы: %w", err)
+	}
+	slog.Info("схема базы данных актуальна", "applied", applied)
+	return nil
+}
+
+// openGeoIP загружает базу GeoIP, если задан geoip.path; иначе возвращает nil.
+func openGeoIP(cfg config.GeoIPConfig) (*geoip.DB, error) {
+	if !cfg.Enabled() {
+		slog.Info("база geoip не настроена, страна посетителя не определяется")
+		return nil, nil
+	}
+	geoDB, err := geoip.Open(cfg.Path, cfg.ReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки базы geoip: %w", err)
+	}
+	return geoDB, nil
+}
+
+// openBlocklist загружает список блокировок доменов, если задан blocklist.path;
+// иначе возвращает nil.
+func openBlocklist(cfg config.BlocklistConfig) (*blocklist.List, error) {
+	if !cfg.Enabled() {
+		slog.Info("список блокировок не настроен, домены ссылок не проверяются")
+		return nil, nil
+	}
+	list, err := blocklist.Open(cfg.Path, cfg.ReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки списка блокировок: %w", err)
+	}
+	return list, nil
+}
+
+// dbSystem возвращает имя СУБД для атрибута db.system.name.
+func dbSystem(driver string) string {
+	if driver == config.StoragePostgres {
+		return "postgresql"
+	}
+	return driver
+}
+
+// NewURLCache создаёт кэш ссылок по конфигурации: локальный LRU и, если настроен,
+// общий Redis за ним. Через Redis реплики узнают об удалённых записях и сбрасывают
+// их в своих локальных кэшах. Возвращённая функция закрывает подписку и соединение
+// с Redis; её нужно вызвать при остановке.
+func NewURLCache(cfg *config.Config) (service.URLCache, func()) {
+	var local cache.Tier = cache.Nop{}
+	if cfg.Cache.Size > 0 {
+		local = cache.NewLocal(cfg.Cache.Size, cfg.Cache.TTL, cfg.Cache.NegativeTTL)
+	}
+	if !cfg.Redis.Enabled() {
+		if cfg.Cache.Size > 0 {
+			slog.Warn("кэш ссылок работает без redis: изменения, сделанные другими репликами и командой admin, "+
+				"применяются на этой реплике с задержкой до cache.ttl", "cache_ttl", cfg.Cache.TTL.String())
+		}
+		return local, func() {}
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Redis.Addr,
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		DialTimeout:  cfg.Redis.Timeout,
+		ReadTimeout:  cfg.Redis.Timeout,
+		WriteTimeout: cfg.Redis.Timeout,
+		// Повторы только удлиняют ожидание: при ошибке запрос и так уйдёт в БД
+		MaxRetries: -1,
+	})
+
+	// Недоступный Redis не мешает запуску: кэш работает в режиме fail-open
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		slog.Warn("redis недоступен, редиректы будут обслуживаться из БД", "addr", cfg.Redis.Addr, "error", err)
+	} else {
+		slog.Info("кэш redis подключён", "addr", cfg.Redis.Addr)
+	}
+
+	redisCache := cache.NewRedis(client, cfg.Redis.TTL, cfg.Redis.NegativeTTL, cfg.Redis.Timeout)
+	invalidations := redisCache.Subscribe(context.Background(), func(code string) {
+		local.Delete(context.Background(), code)
+	})
+
+	closeCache := func() {
+		if err := invalidations.Close(); err != nil {
+			slog.Error("ошибка закрытия подписки на инвалидации кэша", "error", err)
+		}
+		if err := client.Close(); err != nil {
+			slog.Error("ошибка закрытия соединения с redis", "error", err)
+		}
+	}
+	return cache.NewTiered(local, redisCache), closeCache
+}
+
+// newMetricsServer создаёт сервер, который отдаёт только /metrics.
+func newMetricsServer(port string, m *metrics.Metrics) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return &http.Server{
+		Addr:    ":" + port,
+		Handler: mux,
+	}
+}
+
+// Run запускает HTTP-сервер и фоновые обработчики, затем ожидает сигнала завершения.
 func (app *Application) Run() {
 	defer app.cleanup()
 
+	app.janitor.Start()
+	app.clicks.Start()
+	app.aggregator.Start()
+	if app.geoip != nil {
+		app.geoip.Start()
+	}
+	if app.blocklist != nil {
+		app.blocklist.Start()
+	}
+
 	go func() {
 		slog.Info("запуск сервера", "addr", app.server.Addr)
 		if err := app.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -64,6 +333,13 @@ func (app *Application) Run() {
 			os.Exit(1)
 		}
 	}()
+	go func() {
+		slog.Info("запуск сервера метрик", "addr", app.metricsServer.Addr)
+		if err := app.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("ошибка сервера метрик", "error", err)
+			os.Exit(1)
+		}
+	}()
 
 	app.waitForShutdown()
 }
@@ -82,12 +358,38 @@ func (app *Application) waitForShutdown() {
 	if err := app.server.Shutdown(ctx); err != nil {
 		slog.Error("ошибка при остановке сервера", "error", err)
 	}
+	if err := app.metricsServer.Shutdown(ctx); err != nil {
+		slog.Error("ошибка при остановке сервера метрик", "error", err)
+	}
+
+	app.janitor.Stop()
+
+	// Запись переходов останавливается после сервера, чтобы сохранить
+	// события последних обработанных запросов.
+	app.clicks.Stop()
+	slog.Info("запись переходов остановлена", "dropped_total", app.clicks.Dropped())
+
+	app.aggregator.Stop()
+	if app.geoip != nil {
+		app.geoip.Stop()
+	}
+	if app.blocklist != nil {
+		app.blocklist.Stop()
+	}
 
 	slog.Info("сервер остановлен")
 }
 
-// cleanup освобождает ресурсы (закрывает соединение с БД).
+// cleanup освобождает ресурсы (закрывает соединения с БД и Redis, дописывает спаны).
 func (app *Application) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.shutdownTracing(ctx); err != nil {
+		slog.Error("ошибка остановки трассировки", "error", err)
+	}
+
+	app.closeCache()
+
 	if app.db != nil {
 		sqlDB, err := app.db.DB()
 		if err != nil {
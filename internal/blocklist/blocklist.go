@@ -0,0 +1,158 @@
+// Package blocklist проверяет домены ссылок по списку блокировок из файла
+// и перечитывает список при замене файла.
+//
+// Формат файла — по одному правилу на строку, '#' начинает комментарий:
+//
+//	evil.example        # блокирует домен и все его поддомены
+//	!safe.evil.example  # исключение: поддомен разрешён
+//	*                   # блокирует все домены, кроме разрешённых (режим allowlist)
+//
+// Домены сравниваются в форме punycode (IDNA), поэтому правило срабатывает
+// и для записи домена в Unicode, и для её формы xn--.
+package blocklist
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/idna"
+
+	"tinyurl/internal/filewatch"
+)
+
+// rules — разобранный список правил.
+type rules struct {
+	// domains — решение по домену: true — заблокирован, false — разрешён.
+	domains map[string]bool
+	// blockAll — правило '*': блокировать домены без явного разрешения.
+	blockAll bool
+}
+
+// List — список блокировок в памяти процесса. Безопасен для конкурентного
+// использования: перезагрузка подменяет правила атомарно.
+type List struct {
+	path  string
+	rules atomic.Pointer[rules]
+	watch *filewatch.Watcher
+}
+
+// Open загружает список из файла path. reloadInterval — как часто проверять,
+// не изменился ли файл; 0 отключает перезагрузку.
+func Open(path string, reloadInterval time.Duration) (*List, error) {
+	l := &List{path: path}
+	l.watch = filewatch.New("blocklist", path, reloadInterval, l.load)
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Blocked сообщает, заблокирован ли хост. Решает самое точное правило: для
+// a.b.example сначала проверяются a.b.example, затем b.example и example,
+// и только потом '*'.
+func (l *List) Blocked(host string) bool {
+	r := l.rules.Load()
+	host = Normalize(host)
+	if host == "" {
+		return r.blockAll
+	}
+
+	for domain := host; ; {
+		if blocked, ok := r.domains[domain]; ok {
+			return blocked
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return r.blockAll
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// Reload перечитывает файл, если с прошлой загрузки изменились его размер или время
+// изменения. При ошибке продолжает работать прежний список.
+func (l *List) Reload() (bool, error) {
+	return l.watch.Reload()
+}
+
+// Start запускает периодическую проверку файла списка в отдельной горутине.
+func (l *List) Start() {
+	l.watch.Start()
+}
+
+// Stop останавливает проверку файла списка.
+func (l *List) Stop() {
+	l.watch.Stop()
+}
+
+// load разбирает содержимое файла списка и подменяет текущие правила.
+func (l *List) load(data []byte) error {
+	r, err := parse(data)
+	if err != nil {
+		return fmt.Errorf("некорректный список %s: %w", l.path, err)
+	}
+
+	l.rules.Store(r)
+	slog.Info("список блокировок доменов загружен",
+		"path", l.path,
+		"rules", len(r.domains),
+		"block_all", r.blockAll,
+	)
+	return nil
+}
+
+// parse разбирает содержимое файла. Строка с некорректным доменом — ошибка,
+// чтобы опечатка не оставила домен незаблокированным.
+func parse(data []byte) (*rules, error) {
+	r := &rules{domains: make(map[string]bool)}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if text == "*" {
+			r.blockAll = true
+			continue
+		}
+
+		allow := strings.HasPrefix(text, "!")
+		domain := Normalize(strings.TrimPrefix(text, "!"))
+		if domain == "" || strings.ContainsAny(domain, " \t/:@*") {
+			return nil, fmt.Errorf("строка %d: некорректный домен %q", line, text)
+		}
+		r.domains[domain] = !allow
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Normalize приводит хост к виду для сравнения: нижний регистр, без завершающей
+// точки, интернационализированные имена — в punycode. Имя, которое не проходит
+// проверку IDNA, сравнивается как есть в нижнем регистре.
+func Normalize(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// InDomain сообщает, совпадает ли хост с доменом или является его поддоменом.
+// Оба значения сравниваются после Normalize.
+func InDomain(host, domain string) bool {
+	host, domain = Normalize(host), Normalize(domain)
+	if host == "" || domain == "" {
+		return false
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
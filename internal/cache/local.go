@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tinyurl/internal/model"
+)
+
+// Stats — счётчики обращений к кэшу.
+type Stats struct {
+	Hits uint64 `json:"hits"`
+	// NegativeHits — попадания в записи о несуществующих кодах (входят в Hits).
+	NegativeHits uint64 `json:"negative_hits"`
+	Misses       uint64 `json:"misses"`
+	Evictions    uint64 `json:"evictions"`
+	// Entries — число записей в памяти процесса.
+	Entries int `json:"entries"`
+	// Errors — ошибки внешнего хранилища, при которых запрос ушёл в БД.
+	Errors uint64 `json:"errors"`
+}
+
+// Local — кэш записей ссылок в памяти процесса. Записи хранятся копиями,
+// поэтому вызывающий код может свободно изменять полученные структуры.
+// Отсутствие ссылки кэшируется отдельно и на более короткий срок.
+type Local struct {
+	lru         *LRU[string, *model.URL]
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	// mu делает атомарными удаление и условную запись Fill.
+	mu sync.Mutex
+	// gen — поколение кэша, увеличивается при каждом удалении.
+	gen uint64
+
+	hits, negativeHits, misses atomic.Uint64
+}
+
+// NewLocal создаёт кэш на size записей. Найденные ссылки хранятся ttl,
+// отсутствующие коды — negativeTTL (0 отключает кэширование отсутствия).
+func NewLocal(size int, ttl, negativeTTL time.Duration) *Local {
+	return &Local{
+		lru:         NewLRU[string, *model.URL](size),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// Get возвращает ссылку по коду. ok = true и url = nil означает,
+// что код недавно не был найден в БД.
+func (c *Local) Get(_ context.Context, code string) (*model.URL, bool) {
+	url, ok := c.lru.Get(code)
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	if url == nil {
+		c.negativeHits.Add(1)
+		return nil, true
+	}
+	cp := *url
+	cp.Targeting = url.Targeting.Clone()
+	return &cp, true
+}
+
+// Set сохраняет ссылку; url = nil запоминает, что кода нет.
+func (c *Local) Set(_ context.Context, code string, url *model.URL) {
+	c.set(code, url)
+}
+
+// Version возвращает текущее поколение кэша. Поколение общее для всех кодов:
+// удаление любого кода отменяет идущие в этот момент Fill, что проще и
+// безопаснее счётчика на каждый код.
+func (c *Local) Version(context.Context, string) Version {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Version{local: c.gen}
+}
+
+// Fill сохраняет ссылку, если после снятия версии не было удалений.
+func (c *Local) Fill(_ context.Context, code string, url *model.URL, v Version) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != v.local {
+		return
+	}
+	c.set(code, url)
+}
+
+func (c *Local) set(code string, url *model.URL) {
+	if url == nil {
+		if c.negativeTTL > 0 {
+			c.lru.Set(code, nil, c.negativeTTL)
+		}
+		return
+	}
+	cp := *url
+	cp.Targeting = url.Targeting.Clone()
+	c.lru.Set(code, &cp, c.ttl)
+}
+
+// Delete удаляет запись о коде.
+func (c *Local) Delete(_ context.Context, code string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.lru.Delete(code)
+}
+
+// Stats возвращает текущие счётчики кэша.
+func (c *Local) Stats() Stats {
+	return Stats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Evictions:    c.lru.Evictions(),
+		Entries:      c.lru.Len(),
+	}
+}
+
+// Nop — пустой кэш: ничего не хранит, всегда промах. Используется, когда кэш отключён.
+type Nop struct{}
+
+func (Nop) Get(context.Context, string) (*model.URL, bool)    { return nil, false }
+func (Nop) Set(context.Context, string, *model.URL)           {}
+func (Nop) Delete(context.Context, string)                    {}
+func (Nop) Version(context.Context, string) Version           { return Version{} }
+func (Nop) Fill(context.Context, string, *model.URL, Version) {}
+func (Nop) Stats() Stats                                      { return Stats{} }
@@ -0,0 +1,111 @@
+// Package cache содержит кэши записей ссылок для ускорения редиректов.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU — ограниченный по размеру кэш с вытеснением давно неиспользуемых записей
+// и временем жизни у каждой записи. Безопасен для конкурентного использования.
+type LRU[K comparable, V any] struct {
+	size int
+	now  func() time.Time
+
+	mu        sync.Mutex
+	ll        *list.List
+	items     map[K]*list.Element
+	evictions uint64
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// NewLRU создаёт кэш не больше чем на size записей.
+func NewLRU[K comparable, V any](size int) *LRU[K, V] {
+	return NewLRUWithClock[K, V](size, time.Now)
+}
+
+// NewLRUWithClock создаёт кэш с заданным источником времени (для тестов).
+func NewLRUWithClock[K comparable, V any](size int, now func() time.Time) *LRU[K, V] {
+	return &LRU[K, V]{
+		size:  size,
+		now:   now,
+		ll:    list.New(),
+		items: make(map[K]*list.Element, size),
+	}
+}
+
+// Get возвращает значение по ключу. Истёкшая запись удаляется и считается отсутствующей.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	e := el.Value.(*lruEntry[K, V])
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return zero, false
+	}
+
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Set сохраняет значение на время ttl, вытесняя самую давнюю запись при переполнении.
+func (c *LRU[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry[K, V])
+		e.value, e.expires = value, expires
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry[K, V]{key: key, value: value, expires: expires})
+	if c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+		c.evictions++
+	}
+}
+
+// Delete удаляет запись по ключу.
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len возвращает число записей, включая ещё не удалённые истёкшие.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Evictions возвращает число записей, вытесненных из-за переполнения.
+func (c *LRU[K, V]) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
+
+func (c *LRU[K, V]) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*lruEntry[K, V]).key)
+}
@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"tinyurl/internal/model"
+)
+
+// redisKeyPrefix — префикс ключей ссылок в Redis.
+const redisKeyPrefix = "tinyurl:url:"
+
+// redisVersionPrefix — префикс счётчиков инвалидаций кодов.
+const redisVersionPrefix = "tinyurl:ver:"
+
+// redisInvalidateChannel — канал, в который Delete публикует инвалидированные коды,
+// чтобы остальные реплики очистили свои локальные кэши.
+const redisInvalidateChannel = "tinyurl:invalidate"
+
+// redisMissing — значение, которым в Redis помечается отсутствующий код.
+const redisMissing = "-"
+
+// redisUnknownVersion — версия, снятая при ошибке Redis. Не совпадает ни с одним
+// значением счётчика, поэтому Fill с ней ничего не запишет.
+const redisUnknownVersion = "?"
+
+// redisFill записывает значение, только если счётчик инвалидаций кода не изменился.
+// KEYS[1] — запись, KEYS[2] — счётчик; ARGV: ожидаемая версия, значение, TTL в мс
+// (0 — без срока, как у Set).
+var redisFill = redis.NewScript(`
+if (redis.call("GET", KEYS[2]) or "") ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// redisEntry — сериализуемая копия ссылки. model.URL не годится напрямую:
+// хеш пароля скрыт из JSON, а он нужен для проверки при редиректе.
+type redisEntry struct {
+	ID           int64            `json:"id"`
+	ShortURL     string           `json:"short_url"`
+	LongURL      string           `json:"long_url"`
+	CreatedAt    time.Time        `json:"created_at"`
+	ExpiresAt    *time.Time       `json:"expires_at,omitempty"`
+	NotBefore    *time.Time       `json:"not_before,omitempty"`
+	NotAfter     *time.Time       `json:"not_after,omitempty"`
+	MaxClicks    *int64           `json:"max_clicks,omitempty"`
+	PasswordHash string           `json:"password_hash,omitempty"`
+	Disabled     bool             `json:"disabled,omitempty"`
+	RedirectType int              `json:"redirect_type,omitempty"`
+	Targeting    *model.Targeting `json:"targeting,omitempty"`
+}
+
+// Redis — кэш ссылок в Redis, общий для всех реплик. Работает в режиме fail-open:
+// ошибки и таймауты Redis логируются и считаются промахом, редиректы продолжают
+// обслуживаться из БД.
+type Redis struct {
+	client      redis.UniversalClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+	timeout     time.Duration
+
+	hits, negativeHits, misses, errs atomic.Uint64
+}
+
+// NewRedis создаёт кэш поверх клиента Redis. timeout ограничивает каждую операцию,
+// чтобы недоступный Redis не задерживал редиректы.
+func NewRedis(client redis.UniversalClient, ttl, negativeTTL, timeout time.Duration) *Redis {
+	return &Redis{
+		client:      client,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		timeout:     timeout,
+	}
+}
+
+// Get возвращает ссылку по коду; семантика как у Local.Get.
+func (c *Redis) Get(ctx context.Context, code string) (*model.URL, bool) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, redisKeyPrefix+code).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.fail("чтение", err)
+		}
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	if string(data) == redisMissing {
+		c.hits.Add(1)
+		c.negativeHits.Add(1)
+		return nil, true
+	}
+
+	var e redisEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		c.fail("разбор записи", err)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	return &model.URL{
+		ID:           e.ID,
+		ShortURL:     e.ShortURL,
+		LongURL:      e.LongURL,
+		CreatedAt:    e.CreatedAt,
+		ExpiresAt:    e.ExpiresAt,
+		NotBefore:    e.NotBefore,
+		NotAfter:     e.NotAfter,
+		MaxClicks:    e.MaxClicks,
+		PasswordHash: e.PasswordHash,
+		Disabled:     e.Disabled,
+		RedirectType: e.RedirectType,
+		Targeting:    e.Targeting,
+	}, true
+}
+
+// Set сохраняет ссылку; url = nil запоминает, что кода нет.
+func (c *Redis) Set(ctx context.Context, code string, url *model.URL) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	value, ttl, ok := c.encode(url)
+	if !ok {
+		return
+	}
+	if err := c.client.Set(ctx, redisKeyPrefix+code, value, ttl).Err(); err != nil {
+		c.fail("запись", err)
+	}
+}
+
+// Version возвращает счётчик инвалидаций кода.
+func (c *Redis) Version(ctx context.Context, code string) Version {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	v, err := c.client.Get(ctx, redisVersionPrefix+code).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		c.fail("чтение версии", err)
+		return Version{remote: redisUnknownVersion}
+	}
+	return Version{remote: v}
+}
+
+// Fill сохраняет ссылку, если после снятия версии код не инвалидировала ни одна реплика.
+func (c *Redis) Fill(ctx context.Context, code string, url *model.URL, v Version) {
+	if v.remote == redisUnknownVersion {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	value, ttl, ok := c.encode(url)
+	if !ok {
+		return
+	}
+	keys := []string{redisKeyPrefix + code, redisVersionPrefix + code}
+	if err := redisFill.Run(ctx, c.client, keys, v.remote, value, ttl.Milliseconds()).Err(); err != nil {
+		c.fail("запись", err)
+	}
+}
+
+// encode готовит значение записи и её TTL. ok = false — записывать нечего.
+func (c *Redis) encode(url *model.URL) (value []byte, ttl time.Duration, ok bool) {
+	if url == nil {
+		if c.negativeTTL <= 0 {
+			return nil, 0, false
+		}
+		return []byte(redisMissing), c.negativeTTL, true
+	}
+
+	data, err := json.Marshal(redisEntry{
+		ID:           url.ID,
+		ShortURL:     url.ShortURL,
+		LongURL:      url.LongURL,
+		CreatedAt:    url.CreatedAt,
+		ExpiresAt:    url.ExpiresAt,
+		NotBefore:    url.NotBefore,
+		NotAfter:     url.NotAfter,
+		MaxClicks:    url.MaxClicks,
+		PasswordHash: url.PasswordHash,
+		Disabled:     url.Disabled,
+		RedirectType: url.RedirectType,
+		Targeting:    url.Targeting,
+	})
+	if err != nil {
+		c.fail("сериализация записи", err)
+		return nil, 0, false
+	}
+	return data, c.ttl, true
+}
+
+// Delete удаляет запись о коде, увеличивает счётчик его инвалидаций и сообщает
+// об удалении остальным репликам через канал redisInvalidateChannel.
+func (c *Redis) Delete(ctx context.Context, code string) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, redisVersionPrefix+code)
+		// Счётчик нужен, только пока может идти чтение из БД, начатое до удаления
+		if c.ttl > 0 {
+			pipe.Expire(ctx, redisVersionPrefix+code, c.ttl)
+		}
+		pipe.Del(ctx, redisKeyPrefix+code)
+		pipe.Publish(ctx, redisInvalidateChannel, code)
+		return nil
+	})
+	if err != nil {
+		c.fail("удаление", err)
+	}
+}
+
+// Subscribe вызывает onInvalidate для каждого кода, удалённого любой репликой.
+// Подписка восстанавливается после обрывов связи и действует до закрытия
+// возвращённого PubSub.
+func (c *Redis) Subscribe(ctx context.Context, onInvalidate func(code string)) *redis.PubSub {
+	pubsub := c.client.Subscribe(ctx, redisInvalidateChannel)
+	go func() {
+		for msg := range pubsub.Channel() {
+			onInvalidate(msg.Payload)
+		}
+	}()
+	return pubsub
+}
+
+// Stats возвращает счётчики обращений к Redis.
+func (c *Redis) Stats() Stats {
+	return Stats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Errors:       c.errs.Load(),
+	}
+}
+
+func (c *Redis) fail(op string, err error) {
+	c.errs.Add(1)
+	slog.Warn("кэш redis недоступен, используется БД", "op", op, "error", err)
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+
+	"tinyurl/internal/model"
+)
+
+// Tier — уровень многоуровневого кэша.
+type Tier interface {
+	Get(ctx context.Context, code string) (*model.URL, bool)
+	Set(ctx context.Context, code string, url *model.URL)
+	Delete(ctx context.Context, code string)
+	Version(ctx context.Context, code string) Version
+	Fill(ctx context.Context, code string, url *model.URL, v Version)
+	Stats() Stats
+}
+
+// Version — отметка о последней инвалидации кода, снятая перед чтением из БД.
+// Fill сохраняет прочитанную запись, только если с момента снятия отметки код
+// не инвалидировали: иначе устаревшая строка перезаписала бы удаление из кэша.
+type Version struct {
+	// local — поколение локального кэша, растёт при каждом удалении.
+	local uint64
+	// remote — значение счётчика инвалидаций кода в Redis.
+	remote string
+}
+
+// Tiered — двухуровневый кэш: быстрый локальный уровень перед общим (например, Redis).
+// Попадание во второй уровень копируется в первый; запись и удаление идут в оба.
+type Tiered struct {
+	l1, l2 Tier
+}
+
+// NewTiered создаёт двухуровневый кэш.
+func NewTiered(l1, l2 Tier) *Tiered {
+	return &Tiered{l1: l1, l2: l2}
+}
+
+// Get ищет код сначала в первом уровне, затем во втором.
+func (c *Tiered) Get(ctx context.Context, code string) (*model.URL, bool) {
+	if url, ok := c.l1.Get(ctx, code); ok {
+		return url, true
+	}
+
+	// Версию снимаем до чтения второго уровня: если код инвалидируют, пока идёт
+	// запрос к Redis, прочитанная запись не попадёт в первый уровень
+	v := c.l1.Version(ctx, code)
+	url, ok := c.l2.Get(ctx, code)
+	if ok {
+		c.l1.Fill(ctx, code, url, v)
+	}
+	return url, ok
+}
+
+// Set сохраняет запись в оба уровня.
+func (c *Tiered) Set(ctx context.Context, code string, url *model.URL) {
+	c.l2.Set(ctx, code, url)
+	c.l1.Set(ctx, code, url)
+}
+
+// Delete удаляет запись из обоих уровней.
+func (c *Tiered) Delete(ctx context.Context, code string) {
+	c.l2.Delete(ctx, code)
+	c.l1.Delete(ctx, code)
+}
+
+// Version снимает отметки обоих уровней.
+func (c *Tiered) Version(ctx context.Context, code string) Version {
+	return Version{
+		local:  c.l1.Version(ctx, code).local,
+		remote: c.l2.Version(ctx, code).remote,
+	}
+}
+
+// Fill сохраняет прочитанную из БД запись в те уровни, где код не инвалидировали.
+func (c *Tiered) Fill(ctx context.Context, code string, url *model.URL, v Version) {
+	c.l2.Fill(ctx, code, url, v)
+	c.l1.Fill(ctx, code, url, v)
+}
+
+// Stats объединяет счётчики уровней: промахом считается только промах второго уровня.
+func (c *Tiered) Stats() Stats {
+	s1, s2 := c.l1.Stats(), c.l2.Stats()
+	return Stats{
+		Hits:         s1.Hits + s2.Hits,
+		NegativeHits: s1.NegativeHits + s2.NegativeHits,
+		Misses:       s2.Misses,
+		Evictions:    s1.Evictions + s2.Evictions,
+		Entries:      s1.Entries,
+		Errors:       s1.Errors + s2.Errors,
+	}
+}
@@ -0,0 +1,210 @@
+package clicks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tinyurl/internal/model"
+)
+
+const (
+	// defaultQueueSize, defaultBatchSize и defaultFlushInterval используются,
+	// если соответствующие параметры не заданы в конфигурации.
+	defaultQueueSize     = 10000
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+
+	// flushTimeout — сколько ждать записи последней пачки при остановке.
+	flushTimeout = 5 * time.Second
+)
+
+// Event — событие перехода по короткой ссылке.
+type Event struct {
+	URLID     int64
+	ShortURL  string
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	// IP — адрес клиента; в БД сохраняется только его хеш с солью.
+	IP        string
+	RequestID string
+	// Variant — вариант A/B-теста, выбранный для посетителя.
+	Variant string
+	// Country — страна посетителя по базе GeoIP.
+	Country string
+	// Counted — переход уже засчитан в click_count при разрешении ссылки
+	// (ссылки с лимитом переходов считаются синхронно).
+	Counted bool
+}
+
+// Repository — операции хранилища, необходимые для записи переходов.
+type Repository interface {
+	SaveBatch(ctx context.Context, clicks []model.Click, counts map[int64]int64) error
+}
+
+// Config — параметры очереди и фоновой записи.
+type Config struct {
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+	IPSalt        string
+}
+
+// Recorder принимает события переходов в ограниченную очередь в памяти
+// и пачками записывает их в БД в фоновой горутине. Если очередь заполнена,
+// событие отбрасывается и учитывается в счётчике Dropped — редирект не ждёт записи.
+type Recorder struct {
+	repo          Repository
+	queue         chan Event
+	batchSize     int
+	flushInterval time.Duration
+	ipSalt        string
+
+	dropped atomic.Int64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRecorder создаёт приёмник событий переходов.
+func NewRecorder(repo Repository, cfg Config) *Recorder {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	return &Recorder{
+		repo:          repo,
+		queue:         make(chan Event, cfg.QueueSize),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		ipSalt:        cfg.IPSalt,
+		done:          make(chan struct{}),
+	}
+}
+
+// Record ставит событие в очередь без блокировки.
+// Возвращает false, если очередь заполнена и событие отброшено.
+func (r *Recorder) Record(ev Event) bool {
+	select {
+	case r.queue <- ev:
+		return true
+	default:
+		r.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped возвращает число событий, отброшенных из-за заполненной очереди.
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Start запускает фоновую запись событий.
+func (r *Recorder) Start() {
+	r.wg.Add(1)
+	go r.run()
+}
+
+// Stop останавливает запись, предварительно сохранив события, оставшиеся в очереди.
+func (r *Recorder) Stop() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+func (r *Recorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, r.batchSize)
+	var reportedDropped int64
+
+	for {
+		select {
+		case ev := <-r.queue:
+			batch = append(batch, ev)
+			if len(batch) >= r.batchSize {
+				batch = r.flush(batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(batch)
+			if dropped := r.Dropped(); dropped != reportedDropped {
+				slog.Warn("очередь переходов переполнена, события отброшены",
+					"dropped", dropped-reportedDropped,
+					"dropped_total", dropped,
+				)
+				reportedDropped = dropped
+			}
+		case <-r.done:
+			for {
+				select {
+				case ev := <-r.queue:
+					batch = append(batch, ev)
+					if len(batch) >= r.batchSize {
+						batch = r.flush(batch)
+					}
+				default:
+					r.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush сохраняет пачку и возвращает пустой срез для следующей.
+// При ошибке пачка теряется: повторная запись могла бы переполнить память.
+func (r *Recorder) flush(batch []Event) []Event {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	rows := make([]model.Click, 0, len(batch))
+	counts := make(map[int64]int64)
+	for _, ev := range batch {
+		rows = append(rows, model.Click{
+			URLID:     ev.URLID,
+			ShortURL:  ev.ShortURL,
+			ClickedAt: ev.ClickedAt,
+			Referrer:  ev.Referrer,
+			UserAgent: ev.UserAgent,
+			IPHash:    HashIP(r.ipSalt, ev.IP),
+			RequestID: ev.RequestID,
+			Variant:   ev.Variant,
+			Country:   ev.Country,
+		})
+		if !ev.Counted {
+			counts[ev.URLID]++
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if err := r.repo.SaveBatch(ctx, rows, counts); err != nil {
+		slog.Error("ошибка записи переходов", "error", err, "lost", len(rows))
+	}
+
+	return batch[:0]
+}
+
+// HashIP возвращает hex-строку SHA-256 от соли и IP-адреса.
+// Пустой адрес даёт пустую строку.
+func HashIP(salt, ip string) string {
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(salt + ip))
+	return hex.EncodeToString(sum[:])
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf/parsers/yaml"
 	envprovider "github.com/knadh/koanf/providers/env/v2"
@@ -14,8 +15,18 @@ import (
 
 // Config — корневая структура конфигурации приложения.
 type Config struct {
-	App      AppConfig      `koanf:"app"`
-	Postgres PostgresConfig `koanf:"postgres"`
+	App       AppConfig       `koanf:"app"`
+	Storage   StorageConfig   `koanf:"storage"`
+	Postgres  PostgresConfig  `koanf:"postgres"`
+	SQLite    SQLiteConfig    `koanf:"sqlite"`
+	Clicks    ClicksConfig    `koanf:"clicks"`
+	Auth      AuthConfig      `koanf:"auth"`
+	RateLimit RateLimitConfig `koanf:"rate_limit"`
+	Cache     CacheConfig     `koanf:"cache"`
+	Redis     RedisConfig     `koanf:"redis"`
+	Tracing   TracingConfig   `koanf:"tracing"`
+	GeoIP     GeoIPConfig     `koanf:"geoip"`
+	Blocklist BlocklistConfig `koanf:"blocklist"`
 }
 
 // AppConfig — настройки приложения.
@@ -23,6 +34,45 @@ type AppConfig struct {
 	Port          string `koanf:"port"`
 	BaseURL       string `koanf:"base_url"`
 	SnowflakeNode int64  `koanf:"snowflake_node"`
+
+	// MetricsPort — порт отдельного сервера с /metrics. Он слушает отдельно от API,
+	// чтобы метрики не были доступны через публичный балансировщик.
+	MetricsPort string `koanf:"metrics_port"`
+
+	// JanitorInterval — период запуска очистки истёкших ссылок.
+	JanitorInterval time.Duration `koanf:"janitor_interval"`
+	// JanitorBatchSize — сколько истёкших ссылок удалять за один запрос.
+	JanitorBatchSize int `koanf:"janitor_batch_size"`
+
+	// DefaultRedirectType — статус редиректа для ссылок, созданных без redirect_type.
+	DefaultRedirectType int `koanf:"default_redirect_type"`
+	// PermanentRedirectMaxAge — сколько браузеры и прокси могут кэшировать
+	// постоянные редиректы (301, 308); 0 запрещает кэширование.
+	PermanentRedirectMaxAge time.Duration `koanf:"permanent_redirect_max_age"`
+}
+
+// Драйверы хранилища.
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+	StorageMemory   = "memory"
+)
+
+// StorageConfig — выбор бэкенда хранилища.
+type StorageConfig struct {
+	// Driver — postgres (по умолчанию), sqlite (локальный файл, для установок
+	// на одном сервере) или memory: данные в памяти процесса, без сохранения
+	// между перезапусками.
+	Driver string `koanf:"driver"`
+	// SkipMigrations отключает применение миграций при запуске: схему тогда
+	// обновляют отдельно командой admin migrate up.
+	SkipMigrations bool `koanf:"skip_migrations"`
+}
+
+// SQLiteConfig — параметры хранилища SQLite.
+type SQLiteConfig struct {
+	// Path — путь к файлу базы; каталог создаётся при запуске.
+	Path string `koanf:"path"`
 }
 
 // PostgresConfig — параметры подключения к PostgreSQL.
@@ -35,6 +85,128 @@ type PostgresConfig struct {
 	SSLMode  string `koanf:"ssl_mode"`
 }
 
+// ClicksConfig — параметры записи событий переходов.
+type ClicksConfig struct {
+	// QueueSize — ёмкость очереди в памяти; при переполнении события отбрасываются.
+	QueueSize int `koanf:"queue_size"`
+	// BatchSize — сколько событий записывать в БД одним запросом.
+	BatchSize int `koanf:"batch_size"`
+	// FlushInterval — как часто записывать неполную пачку.
+	FlushInterval time.Duration `koanf:"flush_interval"`
+	// IPSalt — соль для хеширования IP-адресов клиентов.
+	IPSalt string `koanf:"ip_salt"`
+
+	// AggregateInterval — период свёртки новых переходов в агрегаты статистики.
+	AggregateInterval time.Duration `koanf:"aggregate_interval"`
+	// AggregateBatchSize — сколько событий агрегировать за одну транзакцию.
+	AggregateBatchSize int `koanf:"aggregate_batch_size"`
+}
+
+// AuthConfig — параметры аутентификации по ключам API.
+type AuthConfig struct {
+	// Required — требовать ключ на маршрутах управления ссылками: изменение, сведения,
+	// история и статистика. Если выключено, ключ проверяется только когда передан,
+	// а анонимные запросы пропускаются.
+	Required bool `koanf:"required"`
+}
+
+// RateLimitConfig — параметры ограничения частоты запросов.
+type RateLimitConfig struct {
+	// TrustedProxies — адреса и подсети прокси (например, nginx), которым разрешено
+	// передавать адрес клиента в X-Forwarded-For.
+	TrustedProxies []string `koanf:"trusted_proxies"`
+	// Policies — политики по группам маршрутов: shorten, manage, redirect.
+	// Отсутствующая политика или requests = 0 отключает ограничение.
+	Policies map[string]RateLimitPolicy `koanf:"policies"`
+}
+
+// RateLimitPolicy — не больше Requests запросов за Period с запасом Burst.
+type RateLimitPolicy struct {
+	Requests int           `koanf:"requests"`
+	Period   time.Duration `koanf:"period"`
+	Burst    int           `koanf:"burst"`
+}
+
+// CacheConfig — параметры кэша ссылок в памяти процесса.
+type CacheConfig struct {
+	// Size — сколько ссылок хранить; 0 отключает кэш.
+	Size int `koanf:"size"`
+	// TTL — сколько хранить найденную ссылку.
+	TTL time.Duration `koanf:"ttl"`
+	// NegativeTTL — сколько помнить, что кода нет (0 — не кэшировать отсутствие).
+	NegativeTTL time.Duration `koanf:"negative_ttl"`
+}
+
+// RedisConfig — параметры общего кэша ссылок в Redis.
+type RedisConfig struct {
+	// Addr — адрес host:port; пустой адрес отключает Redis.
+	Addr     string `koanf:"addr"`
+	Password string `koanf:"password"`
+	DB       int    `koanf:"db"`
+	// TTL и NegativeTTL — как в CacheConfig, но для записей в Redis.
+	TTL         time.Duration `koanf:"ttl"`
+	NegativeTTL time.Duration `koanf:"negative_ttl"`
+	// Timeout — предельное время одной операции; по его истечении запрос идёт в БД.
+	Timeout time.Duration `koanf:"timeout"`
+}
+
+// Enabled сообщает, настроен ли Redis.
+func (r RedisConfig) Enabled() bool {
+	return r.Addr != ""
+}
+
+// Экспортёры трассировок.
+const (
+	TracingNone   = "none"
+	TracingOTLP   = "otlp"
+	TracingStdout = "stdout"
+)
+
+// TracingConfig — параметры трассировки OpenTelemetry.
+type TracingConfig struct {
+	// Exporter — none (по умолчанию), otlp (коллектор по OTLP/HTTP) или stdout
+	// (JSON в стандартный вывод или в файл File — для локальной отладки).
+	Exporter string `koanf:"exporter"`
+	// Endpoint — адрес коллектора host:port; пустой — из OTEL_EXPORTER_OTLP_ENDPOINT
+	// или localhost:4318.
+	Endpoint string `koanf:"endpoint"`
+	// Insecure — подключаться к коллектору без TLS.
+	Insecure bool   `koanf:"insecure"`
+	File     string `koanf:"file"`
+	// SampleRatio — доля новых трасс, которые записываются (0–1]. Решение о записи
+	// трассы, пришедшей в заголовке traceparent, принимает вызывающая сторона.
+	SampleRatio float64 `koanf:"sample_ratio"`
+	ServiceName string  `koanf:"service_name"`
+}
+
+// GeoIPConfig — параметры определения страны посетителя.
+type GeoIPConfig struct {
+	// Path — путь к базе в формате MaxMind (.mmdb), например GeoLite2-Country;
+	// пустой путь отключает определение страны.
+	Path string `koanf:"path"`
+	// ReloadInterval — как часто проверять, не заменён ли файл базы; 0 — не проверять.
+	ReloadInterval time.Duration `koanf:"reload_interval"`
+}
+
+// Enabled сообщает, настроена ли база GeoIP.
+func (g GeoIPConfig) Enabled() bool {
+	return g.Path != ""
+}
+
+// BlocklistConfig — параметры проверки доменов ссылок при сокращении.
+type BlocklistConfig struct {
+	// Path — путь к файлу списка блокировок (формат — в пакете blocklist);
+	// пустой путь отключает проверку.
+	Path string `koanf:"path"`
+	// ReloadInterval — как часто проверять, не изменился ли файл; 0 — не проверять.
+	ReloadInterval time.Duration `koanf:"reload_interval"`
+}
+
+// Enabled сообщает, настроен ли список блокировок.
+func (b BlocklistConfig) Enabled() bool {
+	return b.Path != ""
+}
+
 // DSN формирует строку подключения к PostgreSQL.
 func (p PostgresConfig) DSN() string {
 	return fmt.Sprintf(
@@ -69,15 +241,51 @@ func Load() *Config {
 			key = strings.ToLower(key)
 
 			mapping := map[string]string{
-				"app_port":           "app.port",
-				"app_base_url":       "app.base_url",
-				"app_snowflake_node": "app.snowflake_node",
-				"postgres_host":      "postgres.host",
-				"postgres_port":      "postgres.port",
-				"postgres_user":      "postgres.user",
-				"postgres_password":  "postgres.password",
-				"postgres_db_name":   "postgres.db_name",
-				"postgres_ssl_mode":  "postgres.ssl_mode",
+				"app_port":                       "app.port",
+				"app_metrics_port":               "app.metrics_port",
+				"app_base_url":                   "app.base_url",
+				"app_snowflake_node":             "app.snowflake_node",
+				"app_janitor_interval":           "app.janitor_interval",
+				"app_janitor_batch_size":         "app.janitor_batch_size",
+				"app_default_redirect_type":      "app.default_redirect_type",
+				"app_permanent_redirect_max_age": "app.permanent_redirect_max_age",
+				"storage_driver":                 "storage.driver",
+				"storage_skip_migrations":        "storage.skip_migrations",
+				"sqlite_path":                    "sqlite.path",
+				"postgres_host":                  "postgres.host",
+				"postgres_port":                  "postgres.port",
+				"postgres_user":                  "postgres.user",
+				"postgres_password":              "postgres.password",
+				"postgres_db_name":               "postgres.db_name",
+				"postgres_ssl_mode":              "postgres.ssl_mode",
+				"clicks_queue_size":              "clicks.queue_size",
+				"clicks_batch_size":              "clicks.batch_size",
+				"clicks_flush_interval":          "clicks.flush_interval",
+				"clicks_ip_salt":                 "clicks.ip_salt",
+				"clicks_aggregate_interval":      "clicks.aggregate_interval",
+				"clicks_aggregate_batch_size":    "clicks.aggregate_batch_size",
+				"auth_required":                  "auth.required",
+				"rate_limit_trusted_proxies":     "rate_limit.trusted_proxies",
+				"cache_size":                     "cache.size",
+				"cache_ttl":                      "cache.ttl",
+				"cache_negative_ttl":             "cache.negative_ttl",
+				"redis_addr":                     "redis.addr",
+				"redis_password":                 "redis.password",
+				"redis_db":                       "redis.db",
+				"tracing_exporter":               "tracing.exporter",
+				"tracing_endpoint":               "tracing.endpoint",
+				"tracing_insecure":               "tracing.insecure",
+				"tracing_file":                   "tracing.file",
+				"tracing_sample_ratio":           "tracing.sample_ratio",
+				"geoip_path":                     "geoip.path",
+				"geoip_reload_interval":          "geoip.reload_interval",
+				"blocklist_path":                 "blocklist.path",
+				"blocklist_reload_interval":      "blocklist.reload_interval",
+			}
+
+			// Списки задаются через запятую: RATE_LIMIT_TRUSTED_PROXIES=10.0.0.0/8,127.0.0.1
+			if key == "rate_limit_trusted_proxies" {
+				return mapping[key], strings.Split(value, ",")
 			}
 
 			if mapped, ok := mapping[key]; ok {
@@ -91,6 +299,24 @@ func Load() *Config {
 	if err := k.Unmarshal("", &cfg); err != nil {
 		log.Fatalf("конфиг: ошибка десериализации: %v", err)
 	}
+	if cfg.App.MetricsPort == "" {
+		cfg.App.MetricsPort = "9090"
+	}
+	if cfg.App.DefaultRedirectType == 0 {
+		cfg.App.DefaultRedirectType = 302
+	}
+	if cfg.Storage.Driver == "" {
+		cfg.Storage.Driver = StoragePostgres
+	}
+	if cfg.Tracing.Exporter == "" {
+		cfg.Tracing.Exporter = TracingNone
+	}
+	if cfg.Tracing.SampleRatio <= 0 {
+		cfg.Tracing.SampleRatio = 1
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = "tinyurl"
+	}
 
 	return &cfg
 }
@@ -2,23 +2,80 @@ package db
 
 import (
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
-	"tinyurl/internal/model"
+	"tinyurl/internal/migrate"
+	"tinyurl/migrations"
 )
 
-// Init подключается к PostgreSQL и выполняет автомиграцию схемы.
+// Init подключается к PostgreSQL. Схему создаёт Migrator.
 func Init(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError приводит ошибки драйвера к gorm.ErrDuplicatedKey и т.п.,
+	// чтобы репозиторий мог распознать нарушение уникальности.
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("бд: ошибка подключения: %w", err)
 	}
+	return db, nil
+}
 
-	if err := db.AutoMigrate(&model.URL{}); err != nil {
-		return nil, fmt.Errorf("бд: ошибка миграции: %w", err)
+// InitSQLite открывает (или создаёт) файл SQLite. Схему создаёт Migrator.
+// Используется чистый Go-драйвер, поэтому бинарный файл собирается без cgo.
+func InitSQLite(path string) (*gorm.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("бд: создание каталога %s: %w", dir, err)
+		}
+	}
+
+	// WAL позволяет читать во время записи, busy_timeout — ждать блокировку
+	// вместо немедленной ошибки, foreign_keys включает каскадное удаление истории.
+	dsn := path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		// SQLite хранит время строкой и сравнивает его лексикографически,
+		// поэтому все метки времени записываются в UTC.
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("бд: ошибка открытия sqlite: %w", err)
 	}
 
+	// SQLite допускает одного писателя: одно соединение исключает ошибки
+	// SQLITE_BUSY при одновременных транзакциях.
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("бд: получение sql.DB: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
 	return db, nil
 }
+
+// Migrator создаёт Runner для встроенных миграций из migrations/<dialect>.
+func Migrator(db *gorm.DB, dialect migrate.Dialect) (*migrate.Runner, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("бд: получение sql.DB: %w", err)
+	}
+
+	dir, err := fs.Sub(migrations.FS, string(dialect))
+	if err != nil {
+		return nil, fmt.Errorf("бд: каталог миграций %s: %w", dialect, err)
+	}
+	list, err := migrate.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("бд: нет миграций для %s", dialect)
+	}
+
+	return migrate.New(sqlDB, dialect, list), nil
+}
@@ -1,6 +1,63 @@
 package dto
 
+import "time"
+
 // ShortenRequest — запрос на сокращение ссылки.
 type ShortenRequest struct {
 	LongURL string `json:"long_url" validate:"required,url"`
+	// Alias — желаемый код короткой ссылки (необязательно), например "spring-sale".
+	Alias string `json:"alias,omitempty"`
+	// ExpiresAt — момент истечения ссылки в RFC 3339 (необязательно).
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// TTL — время жизни ссылки в секундах, не больше 10 лет (необязательно, взаимоисключающе с expires_at).
+	TTL int64 `json:"ttl,omitempty"`
+	// NotBefore и NotAfter — окно активности ссылки в RFC 3339 (необязательно): до NotBefore
+	// ссылка отвечает 404, с NotAfter — 410, но в отличие от expires_at не удаляется.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+	// MaxClicks — после скольких переходов ссылка перестаёт работать (необязательно).
+	MaxClicks *int64 `json:"max_clicks,omitempty"`
+	// Password — пароль, который нужно ввести перед переходом (необязательно, 4–72 символа).
+	Password string `json:"password,omitempty"`
+	// RedirectType — HTTP-статус редиректа: 301, 302, 307 или 308 (необязательно,
+	// по умолчанию app.default_redirect_type).
+	RedirectType int `json:"redirect_type,omitempty"`
+	// DeviceTargets — оригинальный URL по платформе посетителя: ios, android, desktop
+	// (необязательно); остальные посетители переходят на long_url.
+	DeviceTargets map[string]string `json:"device_targets,omitempty"`
+	// CountryTargets — оригинальный URL по стране посетителя: ключ — код ISO 3166-1
+	// alpha-2, например "DE" (необязательно; нужна база GeoIP, см. geoip.path).
+	CountryTargets map[string]string `json:"country_targets,omitempty"`
+	// Schedule — оригинальный URL по времени перехода (необязательно, до 20 окон):
+	// действует первое окно [from, to), в которое попадает переход.
+	Schedule []ScheduleWindow `json:"schedule,omitempty"`
+	// Variants — варианты A/B-теста (необязательно, от 2 до 10): посетитель закрепляется
+	// за одним из них пропорционально весам.
+	Variants []Variant `json:"variants,omitempty"`
+}
+
+// ScheduleWindow — окно расписания: URL, действующий с From до To (RFC 3339);
+// одну из границ можно не указывать.
+type ScheduleWindow struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+	URL  string     `json:"url"`
+}
+
+// Variant — вариант A/B-теста: оригинальный URL с весом от 1 до 1000.
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// UpdateURLRequest — запрос на изменение оригинального URL ссылки.
+type UpdateURLRequest struct {
+	LongURL string `json:"long_url" validate:"required,url"`
+}
+
+// RollbackRequest — запрос на откат изменения оригинального URL.
+type RollbackRequest struct {
+	// Version — номер изменения из истории, которое нужно отменить.
+	Version int `json:"version" validate:"required,gt=0"`
 }
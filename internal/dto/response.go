@@ -1,14 +1,96 @@
 package dto
 
+import "time"
+
 // ShortenResponse — ответ с короткой ссылкой.
 type ShortenResponse struct {
-	ShortURL string `json:"short_url"`
+	ShortURL     string     `json:"short_url"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	NotBefore    *time.Time `json:"not_before,omitempty"`
+	NotAfter     *time.Time `json:"not_after,omitempty"`
+	MaxClicks    *int64     `json:"max_clicks,omitempty"`
+	RedirectType int        `json:"redirect_type"`
+	// DeviceTargets — оригинальный URL по платформе посетителя.
+	DeviceTargets  map[string]string `json:"device_targets,omitempty"`
+	CountryTargets map[string]string `json:"country_targets,omitempty"`
+	Schedule       []ScheduleWindow  `json:"schedule,omitempty"`
+	Variants       []Variant         `json:"variants,omitempty"`
+}
+
+// URLResponse — полные сведения о короткой ссылке.
+type URLResponse struct {
+	Code              string     `json:"code"`
+	ShortURL          string     `json:"short_url"`
+	LongURL           string     `json:"long_url"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	NotBefore         *time.Time `json:"not_before,omitempty"`
+	NotAfter          *time.Time `json:"not_after,omitempty"`
+	MaxClicks         *int64     `json:"max_clicks,omitempty"`
+	ClickCount        int64      `json:"click_count"`
+	PasswordProtected bool       `json:"password_protected"`
+	Disabled          bool       `json:"disabled"`
+	RedirectType      int        `json:"redirect_type"`
+	// DeviceTargets — оригинальный URL по платформе посетителя.
+	DeviceTargets  map[string]string `json:"device_targets,omitempty"`
+	CountryTargets map[string]string `json:"country_targets,omitempty"`
+	Schedule       []ScheduleWindow  `json:"schedule,omitempty"`
+	Variants       []Variant         `json:"variants,omitempty"`
+}
+
+// HistoryEntryResponse — одно изменение оригинального URL ссылки.
+type HistoryEntryResponse struct {
+	Version    int       `json:"version"`
+	OldLongURL string    `json:"old_long_url"`
+	NewLongURL string    `json:"new_long_url"`
+	ChangedBy  string    `json:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// StatsPointResponse — число переходов за час или день, начинающийся в time.
+type StatsPointResponse struct {
+	Time   time.Time `json:"time"`
+	Clicks int64     `json:"clicks"`
+}
+
+// StatsBucketResponse — число переходов с данным значением (источник, браузер, ОС, устройство).
+type StatsBucketResponse struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+// StatsResponse — статистика переходов по ссылке.
+type StatsResponse struct {
+	Code        string                `json:"code"`
+	TotalClicks int64                 `json:"total_clicks"`
+	RangeClicks int64                 `json:"range_clicks"`
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	Granularity string                `json:"granularity"`
+	Series      []StatsPointResponse  `json:"series"`
+	Referrers   []StatsBucketResponse `json:"referrers"`
+	Browsers    []StatsBucketResponse `json:"browsers"`
+	OSes        []StatsBucketResponse `json:"oses"`
+	Devices     []StatsBucketResponse `json:"devices"`
+	Countries   []StatsBucketResponse `json:"countries"`
+	Variants    []StatsBucketResponse `json:"variants"`
 }
 
 // HealthResponse — ответ проверки здоровья сервиса.
 type HealthResponse struct {
-	Status string `json:"status"`
-	DB     string `json:"db"`
+	Status string              `json:"status"`
+	DB     string              `json:"db"`
+	Cache  *CacheStatsResponse `json:"cache,omitempty"`
+}
+
+// CacheStatsResponse — счётчики кэша ссылок.
+type CacheStatsResponse struct {
+	Hits         uint64 `json:"hits"`
+	NegativeHits uint64 `json:"negative_hits"`
+	Misses       uint64 `json:"misses"`
+	Evictions    uint64 `json:"evictions"`
+	Entries      int    `json:"entries"`
+	Errors       uint64 `json:"errors"`
 }
 
 // ErrorResponse — ответ с ошибкой.
@@ -0,0 +1,105 @@
+// Package filewatch перечитывает файл при его замене: периодически сравнивает
+// размер и время изменения файла и загружает содержимое только при их смене.
+package filewatch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Watcher следит за одним файлом. Безопасен для конкурентного использования.
+type Watcher struct {
+	name     string
+	path     string
+	interval time.Duration
+	load     func(data []byte) error
+
+	// mu защищает сведения о загруженном файле.
+	mu      sync.Mutex
+	loaded  bool
+	modTime time.Time
+	size    int64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New создаёт наблюдателя за файлом path. name — префикс ошибок и логов (например, "geoip").
+// load разбирает и применяет содержимое файла; при ошибке load должно продолжать
+// работать прежнее состояние. interval — как часто проверять файл; 0 отключает проверку.
+func New(name, path string, interval time.Duration, load func(data []byte) error) *Watcher {
+	return &Watcher{name: name, path: path, interval: interval, load: load}
+}
+
+// Reload перечитывает файл, если с прошлой загрузки изменились его размер или время
+// изменения, и сообщает, было ли загружено новое содержимое. Файл читается в память
+// целиком, поэтому его можно заменять на месте.
+func (w *Watcher) Reload() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", w.name, err)
+	}
+	if w.loaded && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", w.name, err)
+	}
+	if err := w.load(data); err != nil {
+		return false, fmt.Errorf("%s: %w", w.name, err)
+	}
+
+	w.loaded = true
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+	return true, nil
+}
+
+// Start запускает периодическую проверку файла в отдельной горутине.
+func (w *Watcher) Start() {
+	if w.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := w.Reload(); err != nil {
+					slog.Error("ошибка перезагрузки файла", "name", w.name, "error", err)
+				}
+			}
+		}
+	}()
+
+	slog.Info("перезагрузка файла запущена", "name", w.name, "path", w.path, "interval", w.interval.String())
+}
+
+// Stop останавливает проверку файла.
+func (w *Watcher) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
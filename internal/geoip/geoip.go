@@ -0,0 +1,88 @@
+// Package geoip определяет страну клиента по локальной базе MaxMind (.mmdb)
+// и перечитывает базу при замене файла.
+package geoip
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+
+	"tinyurl/internal/filewatch"
+)
+
+// DB — база GeoIP в памяти процесса. Безопасна для конкурентного использования:
+// перезагрузка подменяет базу атомарно, не прерывая текущие запросы.
+type DB struct {
+	path   string
+	reader atomic.Pointer[maxminddb.Reader]
+	watch  *filewatch.Watcher
+}
+
+// Open загружает базу из файла path. reloadInterval — как часто проверять,
+// не изменился ли файл; 0 отключает перезагрузку.
+func Open(path string, reloadInterval time.Duration) (*DB, error) {
+	db := &DB{path: path}
+	db.watch = filewatch.New("geoip", path, reloadInterval, db.load)
+	if _, err := db.Reload(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Country возвращает код страны ISO 3166-1 alpha-2 (например, "DE") для IP-адреса
+// или пустую строку, если адрес некорректен или отсутствует в базе.
+func (db *DB) Country(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+
+	var code string
+	if err := db.reader.Load().Lookup(addr.Unmap()).DecodePath(&code, "country", "iso_code"); err != nil {
+		return ""
+	}
+	return strings.ToUpper(code)
+}
+
+// Reload перечитывает файл, если с прошлой загрузки изменились его размер или время
+// изменения. При ошибке продолжает работать прежняя база.
+func (db *DB) Reload() (bool, error) {
+	return db.watch.Reload()
+}
+
+// Start запускает периодическую проверку файла базы в отдельной горутине.
+func (db *DB) Start() {
+	db.watch.Start()
+}
+
+// Stop останавливает проверку файла базы.
+func (db *DB) Stop() {
+	db.watch.Stop()
+}
+
+// load разбирает содержимое файла базы и подменяет текущую базу.
+func (db *DB) load(data []byte) error {
+	reader, err := maxminddb.OpenBytes(data)
+	if err != nil {
+		return fmt.Errorf("некорректная база %s: %w", db.path, err)
+	}
+
+	db.reader.Store(reader)
+	slog.Info("база geoip загружена",
+		"path", db.path,
+		"type", reader.Metadata.DatabaseType,
+		"build_time", reader.Metadata.BuildTime().UTC(),
+	)
+	return nil
+}
+
+// Nop — заглушка без базы: страна всегда неизвестна.
+type Nop struct{}
+
+// Country возвращает пустую строку.
+func (Nop) Country(string) string { return "" }
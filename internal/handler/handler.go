@@ -3,6 +3,8 @@ package handler
 import (
 	"context"
 
+	"tinyurl/internal/cache"
+	"tinyurl/internal/clicks"
 	"tinyurl/internal/service"
 )
 
@@ -10,7 +12,27 @@ import (
 // Хендлеры зависят от интерфейса, а не от конкретной реализации,
 // что позволяет подставлять моки в тестах.
 type URLService interface {
-	Shorten(ctx context.Context, longURL string) (*service.ShortenResult, error)
-	Resolve(ctx context.Context, shortCode string) (string, error)
+	Shorten(ctx context.Context, params service.ShortenParams) (*service.ShortenResult, error)
+	Resolve(ctx context.Context, shortCode string, visitor service.Visitor) (*service.Resolution, error)
+	Unlock(ctx context.Context, shortCode, password string, visitor service.Visitor) (*service.Resolution, error)
+	Get(ctx context.Context, shortCode string) (*service.URLInfo, error)
+	UpdateDestination(ctx context.Context, shortCode, longURL string) (*service.URLInfo, error)
+	History(ctx context.Context, shortCode string) ([]service.HistoryEntry, error)
+	Rollback(ctx context.Context, shortCode string, version int) (*service.URLInfo, error)
+	SetDisabled(ctx context.Context, shortCode string, disabled bool) (*service.URLInfo, error)
+	Delete(ctx context.Context, shortCode string) error
 	HealthCheck(ctx context.Context) error
+	CacheStats() cache.Stats
+}
+
+// CountryResolver — определение страны клиента по IP-адресу.
+// Пустая строка означает, что страна неизвестна.
+type CountryResolver interface {
+	Country(ip string) string
+}
+
+// ClickRecorder — приёмник событий перехода по ссылке.
+// Record не должен блокировать обработку запроса.
+type ClickRecorder interface {
+	Record(ev clicks.Event) bool
 }
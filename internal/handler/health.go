@@ -17,7 +17,7 @@ func NewHealthHandler(svc URLService) *HealthHandler {
 
 // Health проверяет состояние API и подключение к БД.
 // @Summary     Проверка здоровья
-// @Description Возвращает статус API и подключения к базе данных.
+// @Description Возвращает статус API, подключения к базе данных и счётчики кэша ссылок.
 // @Tags        система
 // @Produce     json
 // @Success     200 {object} dto.HealthResponse
@@ -32,8 +32,17 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		statusCode = http.StatusServiceUnavailable
 	}
 
+	stats := h.svc.CacheStats()
 	writeJSON(w, statusCode, dto.HealthResponse{
 		Status: "ok",
 		DB:     dbStatus,
+		Cache: &dto.CacheStatsResponse{
+			Hits:         stats.Hits,
+			NegativeHits: stats.NegativeHits,
+			Misses:       stats.Misses,
+			Evictions:    stats.Evictions,
+			Entries:      stats.Entries,
+			Errors:       stats.Errors,
+		},
 	})
 }
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"tinyurl/internal/dto"
+	"tinyurl/pkg/qr"
+)
+
+// Параметры QR-кода по умолчанию и допустимые границы.
+const (
+	qrDefaultSize   = 256
+	qrMinSize       = 64
+	qrMaxSize       = 2048
+	qrDefaultMargin = 4
+	qrMaxMargin     = 16
+)
+
+// qrParams — разобранные параметры запроса QR-кода.
+type qrParams struct {
+	format string
+	size   int
+	margin int
+	level  qr.Level
+}
+
+var errInvalidQRParams = errors.New("некорректные параметры qr-кода")
+
+// QR возвращает QR-код полной короткой ссылки.
+// @Summary     QR-код ссылки
+// @Description Рисует QR-код полной короткой ссылки в PNG или SVG. Ответ кэшируется
+// @Description с обязательной перепроверкой по ETag: при совпадении If-None-Match возвращается 304.
+// @Tags        urls
+// @Produce     png
+// @Produce     image/svg+xml
+// @Param       code   path  string true  "Код короткой ссылки"
+// @Param       format query string false "Формат: png или svg (по умолчанию png)"
+// @Param       size   query int    false "Сторона изображения в пикселях (64–2048, по умолчанию 256)"
+// @Param       margin query int    false "Поля в модулях (0–16, по умолчанию 4)"
+// @Param       level  query string false "Уровень коррекции ошибок: L, M, Q или H (по умолчанию M)"
+// @Success     200
+// @Success     304
+// @Failure     400    {object} dto.ErrorResponse
+// @Failure     404    {object} dto.ErrorResponse
+// @Failure     500    {object} dto.ErrorResponse
+// @Router      /api/v1/urls/{code}/qr [get]
+func (h *URLsHandler) QR(w http.ResponseWriter, r *http.Request) {
+	params, err := parseQRParams(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	info, err := h.svc.Get(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		writeManageError(w, err)
+		return
+	}
+
+	etag := qrETag(info.ShortURL, params)
+	w.Header().Set("ETag", etag)
+	// no-cache: кэш хранит картинку, но перепроверяет её по ETag на каждый запрос,
+	// поэтому удалённая или отключённая ссылка сразу перестаёт отдавать QR-код.
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	code, err := qr.Encode(info.ShortURL, params.level)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, dto.ErrorResponse{Error: "не удалось построить qr-код"})
+		return
+	}
+
+	var body []byte
+	switch params.format {
+	case "svg":
+		body = code.SVG(params.size, params.margin)
+		w.Header().Set("Content-Type", "image/svg+xml")
+	default:
+		body, err = code.PNG(params.size, params.margin)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, dto.ErrorResponse{Error: "не удалось построить qr-код"})
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// parseQRParams разбирает query-параметры QR-кода, подставляя значения по умолчанию.
+func parseQRParams(r *http.Request) (qrParams, error) {
+	q := r.URL.Query()
+	p := qrParams{
+		format: "png",
+		size:   qrDefaultSize,
+		margin: qrDefaultMargin,
+		level:  qr.LevelM,
+	}
+
+	if v := q.Get("format"); v != "" {
+		p.format = strings.ToLower(v)
+		if p.format != "png" && p.format != "svg" {
+			return p, errInvalidQRParams
+		}
+	}
+
+	if v := q.Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < qrMinSize || n > qrMaxSize {
+			return p, errInvalidQRParams
+		}
+		p.size = n
+	}
+
+	if v := q.Get("margin"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > qrMaxMargin {
+			return p, errInvalidQRParams
+		}
+		p.margin = n
+	}
+
+	if v := q.Get("level"); v != "" {
+		level, ok := qr.ParseLevel(v)
+		if !ok {
+			return p, errInvalidQRParams
+		}
+		p.level = level
+	}
+
+	return p, nil
+}
+
+// qrETag строит ETag по содержимому и параметрам изображения: одинаковые
+// входные данные всегда дают побайтно одинаковый QR-код.
+func qrETag(content string, p qrParams) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%d|%d|%s", content, p.format, p.size, p.margin, p.level))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches проверяет заголовок If-None-Match на совпадение с etag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
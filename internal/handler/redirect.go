@@ -1,31 +1,84 @@
 package handler
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"html/template"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
 
+	"tinyurl/internal/clicks"
 	"tinyurl/internal/dto"
 	"tinyurl/internal/service"
+	"tinyurl/web"
+)
+
+const (
+	// visitorCookie — cookie с идентификатором посетителя, по которому он
+	// закрепляется за вариантом A/B-теста.
+	visitorCookie       = "tinyurl_vid"
+	visitorCookieMaxAge = 365 * 24 * 60 * 60
+	maxVisitorIDLen     = 64
 )
 
 // RedirectHandler — хендлер редиректа по короткой ссылке.
 type RedirectHandler struct {
-	svc URLService
+	svc    URLService
+	clicks ClickRecorder
+	geo    CountryResolver
+	unlock *template.Template
+	// permanentMaxAge — срок кэширования постоянных редиректов браузерами и прокси.
+	permanentMaxAge time.Duration
 }
 
-func NewRedirectHandler(svc URLService) *RedirectHandler {
-	return &RedirectHandler{svc: svc}
+// NewRedirectHandler создаёт хендлер. geo определяет страну посетителя (geoip.Nop{},
+// если база не подключена), permanentMaxAge ограничивает кэширование редиректов
+// 301 и 308 (0 — не кэшировать).
+func NewRedirectHandler(
+	svc URLService,
+	clicks ClickRecorder,
+	geo CountryResolver,
+	permanentMaxAge time.Duration,
+) *RedirectHandler {
+	tmpl, err := template.ParseFS(web.StaticFS, "static/unlock.html")
+	if err != nil {
+		panic("redirect: не удалось прочитать unlock.html: " + err.Error())
+	}
+	return &RedirectHandler{svc: svc, clicks: clicks, geo: geo, unlock: tmpl, permanentMaxAge: permanentMaxAge}
+}
+
+// unlockPage — данные шаблона страницы ввода пароля.
+type unlockPage struct {
+	Code  string
+	Error string
 }
 
 // Redirect разрешает короткую ссылку и перенаправляет на оригинальный URL.
+// Для ссылки, защищённой паролем, отдаёт HTML-форму ввода пароля.
 // @Summary     Редирект по короткой ссылке
-// @Description Разрешает код короткой ссылки и выполняет 302-редирект на оригинальный URL.
+// @Description Разрешает код короткой ссылки и выполняет редирект на оригинальный URL
+// @Description со статусом, выбранным при создании ссылки (redirect_type, по умолчанию 302).
+// @Description Если у ссылки заданы device_targets, URL выбирается по платформе из User-Agent;
+// @Description если country_targets — по стране из базы GeoIP; если variants — по варианту A/B-теста,
+// @Description закреплённому за посетителем cookie tinyurl_vid; если schedule — по времени перехода.
+// @Description До not_before ссылка отвечает 404, после not_after — 410.
+// @Description Для ссылки с паролем возвращает HTML-форму, отправляемую на POST /{shortURL}.
 // @Tags        urls
 // @Param       shortURL path string true "Код короткой ссылки"
+// @Success     301
 // @Success     302
+// @Success     307
+// @Success     308
+// @Success     200 {string} string "HTML-форма ввода пароля"
 // @Failure     404 {object} dto.ErrorResponse
+// @Failure     410 {object} dto.ErrorResponse
 // @Failure     500 {object} dto.ErrorResponse
 // @Router      /{shortURL} [get]
 func (h *RedirectHandler) Redirect(w http.ResponseWriter, r *http.Request) {
@@ -35,15 +88,179 @@ func (h *RedirectHandler) Redirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	longURL, err := h.svc.Resolve(r.Context(), shortCode)
+	visitor, known := h.visitorFrom(r)
+	res, err := h.svc.Resolve(r.Context(), shortCode, visitor)
 	if err != nil {
-		if errors.Is(err, service.ErrNotFound) {
-			writeJSON(w, http.StatusNotFound, dto.ErrorResponse{Error: "короткая ссылка не найдена"})
+		if errors.Is(err, service.ErrPasswordRequired) {
+			h.renderUnlock(w, http.StatusOK, unlockPage{Code: shortCode})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, dto.ErrorResponse{Error: "не удалось разрешить ссылку"})
+		writeResolveError(w, err)
+		return
+	}
+
+	h.recordClick(r, shortCode, res, visitor)
+	rememberVisitor(w, r, res, visitor, known)
+	h.redirect(w, r, res, res.RedirectType)
+}
+
+// Unlock проверяет пароль защищённой ссылки и перенаправляет на оригинальный URL.
+// @Summary     Переход по ссылке с паролем
+// @Description Принимает пароль из формы и выполняет 303-редирект на оригинальный URL.
+// @Description Неверные попытки ограничены для каждой пары кода и IP клиента. Для ссылки без пароля
+// @Description используется её redirect_type: 307 и 308 сохраняют POST.
+// @Tags        urls
+// @Accept      x-www-form-urlencoded
+// @Param       shortURL path     string true "Код короткой ссылки"
+// @Param       password formData string true "Пароль ссылки"
+// @Success     303
+// @Failure     401 {string} string "HTML-форма с сообщением об ошибке"
+// @Failure     404 {object} dto.ErrorResponse
+// @Failure     410 {object} dto.ErrorResponse
+// @Failure     429 {string} string "HTML-форма с сообщением об ошибке"
+// @Router      /{shortURL} [post]
+func (h *RedirectHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortURL")
+	if shortCode == "" {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "отсутствует короткая ссылка"})
+		return
+	}
+
+	visitor, known := h.visitorFrom(r)
+	res, err := h.svc.Unlock(r.Context(), shortCode, r.PostFormValue("password"), visitor)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWrongPassword):
+			h.renderUnlock(w, http.StatusUnauthorized, unlockPage{Code: shortCode, Error: "Неверный пароль"})
+			return
+		case errors.Is(err, service.ErrTooManyAttempts):
+			h.renderUnlock(w, http.StatusTooManyRequests, unlockPage{
+				Code:  shortCode,
+				Error: "Слишком много попыток. Попробуйте позже",
+			})
+			return
+		}
+		writeResolveError(w, err)
+		return
+	}
+
+	h.recordClick(r, shortCode, res, visitor)
+	rememberVisitor(w, r, res, visitor, known)
+
+	// После формы пароля браузер должен перейти по ссылке GET-запросом
+	status := res.RedirectType
+	if res.PasswordProtected {
+		status = http.StatusSeeOther
+	}
+	h.redirect(w, r, res, status)
+}
+
+// redirect выполняет редирект со статусом status. Постоянные редиректы браузеры
+// кэшируют и дальше ходят мимо сервиса, поэтому срок кэширования ограничивается
+// permanentMaxAge и моментом, когда ответ может измениться (истечение ссылки, смена
+// окна расписания), а ссылки с лимитом переходов не кэшируются.
+// Редирект, выбранный по посетителю, общим кэшам прокси хранить нельзя.
+func (h *RedirectHandler) redirect(w http.ResponseWriter, r *http.Request, res *service.Resolution, status int) {
+	scope := "public"
+	if res.Targeted {
+		scope = "private"
+		w.Header().Set("Vary", "User-Agent, Cookie")
+	}
+	if status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect {
+		maxAge := h.permanentMaxAge
+		if res.ValidUntil != nil {
+			maxAge = min(maxAge, time.Until(*res.ValidUntil))
+		}
+		if res.Counted || maxAge < time.Second {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			w.Header().Set("Cache-Control", scope+", max-age="+strconv.Itoa(int(maxAge.Seconds())))
+		}
+	}
+	http.Redirect(w, r, res.LongURL, status)
+}
+
+// visitorFrom собирает сведения о посетителе для выбора оригинального URL.
+// Страна определяется по адресу клиента. Идентификатор берётся из cookie visitorCookie (known = true), а при её отсутствии
+// вычисляется из адреса и User-Agent, чтобы и клиенты без cookie попадали
+// в один и тот же вариант A/B-теста.
+func (h *RedirectHandler) visitorFrom(r *http.Request) (visitor service.Visitor, known bool) {
+	ip := clientIP(r)
+	visitor.IP = ip
+	visitor.UserAgent = r.UserAgent()
+	visitor.Country = h.geo.Country(ip)
+	if c, err := r.Cookie(visitorCookie); err == nil && c.Value != "" && len(c.Value) <= maxVisitorIDLen {
+		visitor.ID = c.Value
+		return visitor, true
+	}
+	sum := sha256.Sum256([]byte(ip + "\x00" + visitor.UserAgent))
+	visitor.ID = hex.EncodeToString(sum[:16])
+	return visitor, false
+}
+
+// rememberVisitor сохраняет идентификатор посетителя в cookie, когда ему впервые
+// выбран вариант A/B-теста, чтобы следующие переходы вели на тот же вариант.
+func rememberVisitor(w http.ResponseWriter, r *http.Request, res *service.Resolution, visitor service.Visitor, known bool) {
+	if res.Variant == "" || known {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    visitor.ID,
+		Path:     "/",
+		MaxAge:   visitorCookieMaxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// recordClick передаёт событие перехода в очередь записи, не дожидаясь сохранения.
+func (h *RedirectHandler) recordClick(r *http.Request, shortCode string, res *service.Resolution, visitor service.Visitor) {
+	h.clicks.Record(clicks.Event{
+		URLID:     res.URLID,
+		ShortURL:  shortCode,
+		ClickedAt: time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+		RequestID: chimw.GetReqID(r.Context()),
+		Variant:   res.Variant,
+		Country:   visitor.Country,
+		Counted:   res.Counted,
+	})
+}
+
+// renderUnlock отдаёт страницу ввода пароля. Шаблон выполняется в буфер, чтобы
+// при ошибке ответить 500, а не обрывать страницу на середине.
+func (h *RedirectHandler) renderUnlock(w http.ResponseWriter, status int, page unlockPage) {
+	var buf bytes.Buffer
+	if err := h.unlock.Execute(&buf, page); err != nil {
+		slog.Error("ошибка отображения формы пароля", "code", page.Code, "error", err)
+		http.Error(w, "не удалось отобразить форму пароля", http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, longURL, http.StatusFound)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// writeResolveError записывает ответ для ошибки разрешения ссылки.
+func writeResolveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, dto.ErrorResponse{Error: "короткая ссылка не найдена"})
+	case errors.Is(err, service.ErrNotYetActive):
+		writeJSON(w, http.StatusNotFound, dto.ErrorResponse{Error: "ссылка ещё не активна"})
+	case errors.Is(err, service.ErrDisabled):
+		writeJSON(w, http.StatusGone, dto.ErrorResponse{Error: "ссылка отключена"})
+	case errors.Is(err, service.ErrExpired):
+		writeJSON(w, http.StatusGone, dto.ErrorResponse{Error: "срок действия ссылки истёк"})
+	case errors.Is(err, service.ErrClickLimitReached):
+		writeJSON(w, http.StatusGone, dto.ErrorResponse{Error: "лимит переходов по ссылке исчерпан"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, dto.ErrorResponse{Error: "не удалось разрешить ссылку"})
+	}
 }
@@ -2,13 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 
 	"tinyurl/internal/dto"
+	"tinyurl/internal/service"
 )
 
+// maxTTL — наибольшее время жизни ссылки, которое можно задать через ttl.
+// Ограничение не даёт переполнить time.Duration при переводе секунд.
+const maxTTL = 10 * 365 * 24 * time.Hour
+
 // ShortenHandler — хендлер создания короткой ссылки.
 type ShortenHandler struct {
 	svc      URLService
@@ -25,13 +32,26 @@ func NewShortenHandler(svc URLService) *ShortenHandler {
 // Shorten создаёт короткую ссылку из длинного URL.
 // @Summary     Сокращение ссылки
 // @Description Создаёт короткую ссылку. Если длинный URL уже сокращался — возвращает существующую.
+// @Description Необязательный alias задаёт собственный код (3–12 символов: латиница, цифры, '-', '_').
+// @Description Срок действия задаётся через expires_at (RFC 3339) или ttl (секунды, не больше 10 лет),
+// @Description лимит переходов — через max_clicks, пароль для перехода — через password.
+// @Description redirect_type задаёт статус редиректа: 301/308 — постоянный, 302/307 — временный.
+// @Description device_targets задаёт отдельные URL для платформ ios, android и desktop,
+// @Description country_targets — для стран (ISO 3166-1 alpha-2), schedule — по времени перехода,
+// @Description variants — варианты A/B-теста с весами. not_before/not_after задают окно активности.
+// @Description Ссылка на домен из списка блокировок отклоняется с 422.
 // @Tags        urls
 // @Accept      json
 // @Produce     json
 // @Param       request body     dto.ShortenRequest  true "Длинный URL для сокращения"
 // @Success     201     {object} dto.ShortenResponse
 // @Failure     400     {object} dto.ErrorResponse
+// @Failure     401     {object} dto.ErrorResponse
+// @Failure     409     {object} dto.ErrorResponse
+// @Failure     422     {object} dto.ErrorResponse
+// @Failure     429     {object} dto.ErrorResponse
 // @Failure     500     {object} dto.ErrorResponse
+// @Security    BearerAuth
 // @Router      /api/v1/shorten [post]
 func (h *ShortenHandler) Shorten(w http.ResponseWriter, r *http.Request) {
 	var req dto.ShortenRequest
@@ -45,11 +65,125 @@ func (h *ShortenHandler) Shorten(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.svc.Shorten(r.Context(), req.LongURL)
+	if req.TTL > int64(maxTTL/time.Second) {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "ttl не может превышать 10 лет"})
+		return
+	}
+
+	result, err := h.svc.Shorten(r.Context(), service.ShortenParams{
+		LongURL:        req.LongURL,
+		Alias:          req.Alias,
+		ExpiresAt:      req.ExpiresAt,
+		NotBefore:      req.NotBefore,
+		NotAfter:       req.NotAfter,
+		TTL:            time.Duration(req.TTL) * time.Second,
+		MaxClicks:      req.MaxClicks,
+		Password:       req.Password,
+		RedirectType:   req.RedirectType,
+		DeviceTargets:  req.DeviceTargets,
+		CountryTargets: req.CountryTargets,
+		Schedule:       fromScheduleDTOs(req.Schedule),
+		Variants:       fromVariantDTOs(req.Variants),
+	})
 	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAlias):
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "некорректный alias"})
+			return
+		case errors.Is(err, service.ErrAliasTaken):
+			writeJSON(w, http.StatusConflict, dto.ErrorResponse{Error: "alias уже занят"})
+			return
+		case errors.Is(err, service.ErrInvalidExpiry):
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "некорректный срок действия ссылки"})
+			return
+		case errors.Is(err, service.ErrInvalidMaxClicks):
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "max_clicks должен быть положительным"})
+			return
+		case errors.Is(err, service.ErrInvalidRedirectType):
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "redirect_type должен быть 301, 302, 307 или 308"})
+			return
+		case errors.Is(err, service.ErrInvalidTargeting):
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{
+				Error: "некорректные device_targets, country_targets или variants: платформы ios, android, desktop; " +
+					"коды стран ISO 3166-1 alpha-2; " +
+					"от 2 до 10 вариантов с разными именами и весом 1–1000; URL http(s)",
+			})
+			return
+		case errors.Is(err, service.ErrInvalidSchedule):
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{
+				Error: "некорректное окно активности или schedule: not_before раньше not_after, not_after в будущем; " +
+					"до 20 окон с хотя бы одной границей, from раньше to, URL http(s)",
+			})
+			return
+		case errors.Is(err, service.ErrBlockedDomain):
+			writeJSON(w, http.StatusUnprocessableEntity, dto.ErrorResponse{Error: "ссылка ведёт на заблокированный домен"})
+			return
+		case errors.Is(err, service.ErrInvalidPassword):
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "пароль должен содержать от 4 до 72 символов"})
+			return
+		}
 		writeJSON(w, http.StatusInternalServerError, dto.ErrorResponse{Error: "не удалось сократить ссылку"})
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, dto.ShortenResponse{ShortURL: result.ShortURL})
+	writeJSON(w, http.StatusCreated, dto.ShortenResponse{
+		ShortURL:       result.ShortURL,
+		ExpiresAt:      result.ExpiresAt,
+		NotBefore:      result.NotBefore,
+		NotAfter:       result.NotAfter,
+		MaxClicks:      result.MaxClicks,
+		RedirectType:   result.RedirectType,
+		DeviceTargets:  result.DeviceTargets,
+		CountryTargets: result.CountryTargets,
+		Schedule:       toScheduleDTOs(result.Schedule),
+		Variants:       toVariantDTOs(result.Variants),
+	})
+}
+
+// fromScheduleDTOs преобразует окна расписания из запроса.
+func fromScheduleDTOs(windows []dto.ScheduleWindow) []service.ScheduleWindow {
+	if len(windows) == 0 {
+		return nil
+	}
+	out := make([]service.ScheduleWindow, 0, len(windows))
+	for _, w := range windows {
+		out = append(out, service.ScheduleWindow{From: w.From, To: w.To, URL: w.URL})
+	}
+	return out
+}
+
+// toScheduleDTOs преобразует окна расписания для ответа.
+func toScheduleDTOs(windows []service.ScheduleWindow) []dto.ScheduleWindow {
+	if len(windows) == 0 {
+		return nil
+	}
+	out := make([]dto.ScheduleWindow, 0, len(windows))
+	for _, w := range windows {
+		out = append(out, dto.ScheduleWindow{From: w.From, To: w.To, URL: w.URL})
+	}
+	return out
+}
+
+// fromVariantDTOs преобразует варианты A/B-теста из запроса.
+func fromVariantDTOs(variants []dto.Variant) []service.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]service.Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, service.Variant{Name: v.Name, URL: v.URL, Weight: v.Weight})
+	}
+	return out
+}
+
+// toVariantDTOs преобразует варианты A/B-теста для ответа.
+func toVariantDTOs(variants []service.Variant) []dto.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]dto.Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, dto.Variant{Name: v.Name, URL: v.URL, Weight: v.Weight})
+	}
+	return out
 }
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"tinyurl/internal/dto"
+	"tinyurl/internal/service"
+)
+
+// StatsService — интерфейс сервиса статистики переходов.
+type StatsService interface {
+	Stats(ctx context.Context, shortCode string, q service.StatsQuery) (*service.Stats, error)
+}
+
+// StatsHandler — хендлер статистики переходов по ссылке.
+type StatsHandler struct {
+	svc StatsService
+}
+
+func NewStatsHandler(svc StatsService) *StatsHandler {
+	return &StatsHandler{svc: svc}
+}
+
+// Stats возвращает статистику переходов по ссылке.
+// @Summary     Статистика ссылки
+// @Description Возвращает общее число переходов, ряд по часам или дням за интервал
+// @Description и топ источников, браузеров, ОС и классов устройств. Данные строятся
+// @Description фоновой агрегацией и появляются с небольшой задержкой.
+// @Tags        urls
+// @Produce     json
+// @Param       code        path     string true  "Код короткой ссылки"
+// @Param       from        query    string false "Начало интервала (RFC 3339), по умолчанию to − 7 дней"
+// @Param       to          query    string false "Конец интервала (RFC 3339), по умолчанию сейчас"
+// @Param       granularity query    string false "Шаг ряда: hour или day (по умолчанию day)"
+// @Param       top         query    int    false "Сколько значений в разбивках (1–100, по умолчанию 10)"
+// @Success     200         {object} dto.StatsResponse
+// @Failure     400         {object} dto.ErrorResponse
+// @Failure     401         {object} dto.ErrorResponse
+// @Failure     404         {object} dto.ErrorResponse
+// @Failure     500         {object} dto.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/v1/urls/{code}/stats [get]
+func (h *StatsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	q, err := parseStatsQuery(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "некорректные параметры статистики"})
+		return
+	}
+
+	stats, err := h.svc.Stats(r.Context(), chi.URLParam(r, "code"), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidStatsQuery):
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "некорректные параметры статистики"})
+		case errors.Is(err, service.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, dto.ErrorResponse{Error: "короткая ссылка не найдена"})
+		default:
+			writeJSON(w, http.StatusInternalServerError, dto.ErrorResponse{Error: "не удалось получить статистику"})
+		}
+		return
+	}
+
+	resp := dto.StatsResponse{
+		Code:        stats.Code,
+		TotalClicks: stats.TotalClicks,
+		RangeClicks: stats.RangeClicks,
+		From:        stats.From,
+		To:          stats.To,
+		Granularity: stats.Granularity,
+		Series:      make([]dto.StatsPointResponse, 0, len(stats.Series)),
+		Referrers:   toBucketResponses(stats.Referrers),
+		Browsers:    toBucketResponses(stats.Browsers),
+		OSes:        toBucketResponses(stats.OSes),
+		Devices:     toBucketResponses(stats.Devices),
+		Countries:   toBucketResponses(stats.Countries),
+		Variants:    toBucketResponses(stats.Variants),
+	}
+	for _, p := range stats.Series {
+		resp.Series = append(resp.Series, dto.StatsPointResponse{Time: p.Time, Clicks: p.Clicks})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseStatsQuery читает параметры статистики из query-строки.
+func parseStatsQuery(r *http.Request) (service.StatsQuery, error) {
+	var q service.StatsQuery
+	values := r.URL.Query()
+
+	if v := values.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return q, err
+		}
+		q.From = t
+	}
+	if v := values.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return q, err
+		}
+		q.To = t
+	}
+	if v := values.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return q, err
+		}
+		q.Top = n
+	}
+	q.Granularity = values.Get("granularity")
+
+	return q, nil
+}
+
+func toBucketResponses(buckets []service.StatsBucket) []dto.StatsBucketResponse {
+	resp := make([]dto.StatsBucketResponse, 0, len(buckets))
+	for _, b := range buckets {
+		resp = append(resp, dto.StatsBucketResponse{Value: b.Value, Clicks: b.Clicks})
+	}
+	return resp
+}
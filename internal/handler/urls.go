@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+
+	"tinyurl/internal/dto"
+	"tinyurl/internal/middleware"
+	"tinyurl/internal/service"
+)
+
+// URLsHandler — хендлер управления короткими ссылками.
+type URLsHandler struct {
+	svc      URLService
+	validate *validator.Validate
+}
+
+func NewURLsHandler(svc URLService) *URLsHandler {
+	return &URLsHandler{
+		svc:      svc,
+		validate: validator.New(),
+	}
+}
+
+// Get возвращает сведения о короткой ссылке.
+// @Summary     Сведения о ссылке
+// @Description Возвращает оригинальный URL, дату создания, число переходов и флаги ссылки.
+// @Description Для защищённой паролем ссылки адреса назначения видны только с ключом API.
+// @Tags        urls
+// @Produce     json
+// @Param       code path     string true "Код короткой ссылки"
+// @Success     200  {object} dto.URLResponse
+// @Failure     401  {object} dto.ErrorResponse
+// @Failure     404  {object} dto.ErrorResponse
+// @Failure     500  {object} dto.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/v1/urls/{code} [get]
+func (h *URLsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	info, err := h.svc.Get(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		writeManageError(w, err)
+		return
+	}
+
+	resp := toURLResponse(info)
+	if info.PasswordProtected && middleware.CallerFrom(r.Context()) == nil {
+		redactDestinations(&resp)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Update меняет оригинальный URL короткой ссылки.
+// @Summary     Изменение оригинального URL
+// @Tags        urls
+// @Accept      json
+// @Produce     json
+// @Param       code    path     string               true "Код короткой ссылки"
+// @Param       request body     dto.UpdateURLRequest true "Новый оригинальный URL"
+// @Success     200     {object} dto.URLResponse
+// @Failure     400     {object} dto.ErrorResponse
+// @Failure     401     {object} dto.ErrorResponse
+// @Failure     404     {object} dto.ErrorResponse
+// @Failure     422     {object} dto.ErrorResponse
+// @Failure     500     {object} dto.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/v1/urls/{code} [patch]
+func (h *URLsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpdateURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "некорректное тело запроса"})
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "некорректный или отсутствующий long_url"})
+		return
+	}
+
+	info, err := h.svc.UpdateDestination(r.Context(), chi.URLParam(r, "code"), req.LongURL)
+	if err != nil {
+		writeManageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toURLResponse(info))
+}
+
+// History возвращает историю изменений оригинального URL.
+// @Summary     История изменений ссылки
+// @Description Для защищённой паролем ссылки адреса в истории видны только с ключом API.
+// @Tags        urls
+// @Produce     json
+// @Param       code path     string true "Код короткой ссылки"
+// @Success     200  {array}  dto.HistoryEntryResponse
+// @Failure     401  {object} dto.ErrorResponse
+// @Failure     404  {object} dto.ErrorResponse
+// @Failure     500  {object} dto.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/v1/urls/{code}/history [get]
+func (h *URLsHandler) History(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	entries, err := h.svc.History(r.Context(), code)
+	if err != nil {
+		writeManageError(w, err)
+		return
+	}
+
+	redact := false
+	if middleware.CallerFrom(r.Context()) == nil {
+		info, err := h.svc.Get(r.Context(), code)
+		if err != nil {
+			writeManageError(w, err)
+			return
+		}
+		redact = info.PasswordProtected
+	}
+
+	resp := make([]dto.HistoryEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		entry := dto.HistoryEntryResponse{
+			Version:    e.Version,
+			OldLongURL: e.OldLongURL,
+			NewLongURL: e.NewLongURL,
+			ChangedBy:  e.ChangedBy,
+			ChangedAt:  e.ChangedAt,
+		}
+		if redact {
+			entry.OldLongURL, entry.NewLongURL = "", ""
+		}
+		resp = append(resp, entry)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Rollback отменяет изменение оригинального URL с указанной версией.
+// @Summary     Откат изменения ссылки
+// @Description Возвращает оригинальный URL, действовавший до изменения с указанной версией.
+// @Description Откат записывается в историю как новая версия.
+// @Tags        urls
+// @Accept      json
+// @Produce     json
+// @Param       code    path     string              true "Код короткой ссылки"
+// @Param       request body     dto.RollbackRequest true "Версия для отката"
+// @Success     200     {object} dto.URLResponse
+// @Failure     400     {object} dto.ErrorResponse
+// @Failure     401     {object} dto.ErrorResponse
+// @Failure     404     {object} dto.ErrorResponse
+// @Failure     422     {object} dto.ErrorResponse
+// @Failure     500     {object} dto.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/v1/urls/{code}/rollback [post]
+func (h *URLsHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	var req dto.RollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "некорректное тело запроса"})
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Error: "некорректный или отсутствующий version"})
+		return
+	}
+
+	info, err := h.svc.Rollback(r.Context(), chi.URLParam(r, "code"), req.Version)
+	if err != nil {
+		writeManageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toURLResponse(info))
+}
+
+// Disable отключает короткую ссылку.
+// @Summary     Отключение ссылки
+// @Description Ссылка сохраняется, но редирект по ней возвращает 410.
+// @Tags        urls
+// @Produce     json
+// @Param       code path     string true "Код короткой ссылки"
+// @Success     200  {object} dto.URLResponse
+// @Failure     401  {object} dto.ErrorResponse
+// @Failure     404  {object} dto.ErrorResponse
+// @Failure     500  {object} dto.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/v1/urls/{code}/disable [post]
+func (h *URLsHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+// Enable включает ранее отключённую короткую ссылку.
+// @Summary     Включение ссылки
+// @Tags        urls
+// @Produce     json
+// @Param       code path     string true "Код короткой ссылки"
+// @Success     200  {object} dto.URLResponse
+// @Failure     401  {object} dto.ErrorResponse
+// @Failure     404  {object} dto.ErrorResponse
+// @Failure     500  {object} dto.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/v1/urls/{code}/enable [post]
+func (h *URLsHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *URLsHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	info, err := h.svc.SetDisabled(r.Context(), chi.URLParam(r, "code"), disabled)
+	if err != nil {
+		writeManageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toURLResponse(info))
+}
+
+// Delete удаляет короткую ссылку.
+// @Summary     Удаление ссылки
+// @Tags        urls
+// @Param       code path string true "Код короткой ссылки"
+// @Success     204
+// @Failure     401 {object} dto.ErrorResponse
+// @Failure     404 {object} dto.ErrorResponse
+// @Failure     500 {object} dto.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/v1/urls/{code} [delete]
+func (h *URLsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Delete(r.Context(), chi.URLParam(r, "code")); err != nil {
+		writeManageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeManageError записывает ответ для ошибки API управления ссылками.
+func writeManageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, dto.ErrorResponse{Error: "короткая ссылка не найдена"})
+	case errors.Is(err, service.ErrVersionNotFound):
+		writeJSON(w, http.StatusNotFound, dto.ErrorResponse{Error: "версия не найдена в истории ссылки"})
+	case errors.Is(err, service.ErrBlockedDomain):
+		writeJSON(w, http.StatusUnprocessableEntity, dto.ErrorResponse{Error: "ссылка ведёт на заблокированный домен"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, dto.ErrorResponse{Error: "не удалось выполнить операцию"})
+	}
+}
+
+// toURLResponse преобразует сведения о ссылке в DTO ответа.
+// redactDestinations скрывает адреса назначения ссылки. Применяется к защищённым
+// паролем ссылкам при анонимном запросе (auth.required = false), чтобы пароль
+// нельзя было обойти, прочитав адрес через API. История скрывается так же (см. History).
+func redactDestinations(resp *dto.URLResponse) {
+	resp.LongURL = ""
+	resp.DeviceTargets = nil
+	resp.CountryTargets = nil
+	resp.Schedule = nil
+	resp.Variants = nil
+}
+
+func toURLResponse(info *service.URLInfo) dto.URLResponse {
+	return dto.URLResponse{
+		Code:              info.Code,
+		ShortURL:          info.ShortURL,
+		LongURL:           info.LongURL,
+		CreatedAt:         info.CreatedAt,
+		ExpiresAt:         info.ExpiresAt,
+		NotBefore:         info.NotBefore,
+		NotAfter:          info.NotAfter,
+		MaxClicks:         info.MaxClicks,
+		ClickCount:        info.ClickCount,
+		PasswordProtected: info.PasswordProtected,
+		Disabled:          info.Disabled,
+		RedirectType:      info.RedirectType,
+		DeviceTargets:     info.DeviceTargets,
+		CountryTargets:    info.CountryTargets,
+		Schedule:          toScheduleDTOs(info.Schedule),
+		Variants:          toVariantDTOs(info.Variants),
+	}
+}
@@ -0,0 +1,102 @@
+package janitor
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Repository — операции хранилища, необходимые для очистки.
+type Repository interface {
+	DeleteExpired(ctx context.Context, now time.Time, limit int) (int64, error)
+}
+
+// defaultBatchSize — размер пачки, если в конфигурации он не задан.
+const defaultBatchSize = 500
+
+// Janitor — фоновый обработчик, периодически удаляющий ссылки с истёкшим сроком действия.
+type Janitor struct {
+	repo      Repository
+	interval  time.Duration
+	batchSize int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New создаёт обработчик очистки с заданным интервалом запуска и размером пачки.
+// Нулевой интервал отключает периодическую очистку.
+func New(repo Repository, interval time.Duration, batchSize int) *Janitor {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return &Janitor{
+		repo:      repo,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Start запускает периодическую очистку в отдельной горутине.
+func (j *Janitor) Start() {
+	if j.interval <= 0 {
+		slog.Info("очистка истёкших ссылок отключена")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce(ctx)
+			}
+		}
+	}()
+
+	slog.Info("очистка истёкших ссылок запущена", "interval", j.interval.String(), "batch_size", j.batchSize)
+}
+
+// Stop останавливает очистку и дожидается завершения текущего прохода.
+func (j *Janitor) Stop() {
+	if j.cancel == nil {
+		return
+	}
+	j.cancel()
+	j.wg.Wait()
+}
+
+// RunOnce удаляет истёкшие ссылки пачками, пока они не закончатся,
+// и возвращает общее число удалённых записей.
+func (j *Janitor) RunOnce(ctx context.Context) int64 {
+	now := time.Now()
+
+	var total int64
+	for ctx.Err() == nil {
+		deleted, err := j.repo.DeleteExpired(ctx, now, j.batchSize)
+		if err != nil {
+			slog.Error("ошибка очистки истёкших ссылок", "error", err, "deleted", total)
+			return total
+		}
+		total += deleted
+		if deleted < int64(j.batchSize) {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.Info("истёкшие ссылки удалены", "deleted", total)
+	}
+	return total
+}
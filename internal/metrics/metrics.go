@@ -0,0 +1,192 @@
+// Package metrics собирает метрики сервиса в формате Prometheus.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+
+	"tinyurl/internal/cache"
+)
+
+const namespace = "tinyurl"
+
+// Metrics — реестр метрик сервиса. Реализует service.Metrics и
+// middleware.RequestObserver.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	shortens  *prometheus.CounterVec
+	redirects prometheus.Counter
+	notFound  prometheus.Counter
+	ids       prometheus.Counter
+	dbErrors  *prometheus.CounterVec
+}
+
+// New создаёт реестр с метриками HTTP, событий сервиса, ошибок БД и рантайма Go.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Число HTTP-запросов по методу, шаблону маршрута и статусу.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Длительность обработки HTTP-запросов по методу и шаблону маршрута.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method", "route"}),
+		shortens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shortens_total",
+			Help:      "Сокращённые ссылки: created — новая, deduplicated — возвращена существующая.",
+		}, []string{"result"}),
+		redirects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Разрешённые переходы по коротким ссылкам.",
+		}),
+		notFound: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "not_found_total",
+			Help:      "Переходы по неизвестным кодам.",
+		}),
+		ids: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "snowflake_ids_total",
+			Help:      "Выданные Snowflake ID.",
+		}),
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_errors_total",
+			Help:      "Ошибки запросов к базе данных по типу операции.",
+		}, []string{"operation"}),
+	}
+
+	// Ряды с метками создаются заранее, чтобы быть видимыми до первого события
+	m.shortens.WithLabelValues("created")
+	m.shortens.WithLabelValues("deduplicated")
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.shortens, m.redirects, m.notFound, m.ids, m.dbErrors,
+	)
+	return m
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest учитывает обработанный HTTP-запрос.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.duration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// Shortened учитывает сокращение ссылки.
+func (m *Metrics) Shortened(deduplicated bool) {
+	if deduplicated {
+		m.shortens.WithLabelValues("deduplicated").Inc()
+		return
+	}
+	m.shortens.WithLabelValues("created").Inc()
+}
+
+// Redirected учитывает разрешённый переход.
+func (m *Metrics) Redirected() { m.redirects.Inc() }
+
+// NotFound учитывает переход по неизвестному коду.
+func (m *Metrics) NotFound() { m.notFound.Inc() }
+
+// IDGenerated учитывает выданный Snowflake ID.
+func (m *Metrics) IDGenerated() { m.ids.Inc() }
+
+// RegisterCache экспортирует счётчики кэша ссылок; stats вызывается при каждом сборе.
+func (m *Metrics) RegisterCache(stats func() cache.Stats) {
+	m.registry.MustRegister(&cacheCollector{stats: stats})
+}
+
+// RegisterClicksDropped экспортирует число отброшенных событий переходов.
+func (m *Metrics) RegisterClicksDropped(dropped func() int64) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "clicks_dropped_total",
+		Help:      "События переходов, отброшенные из-за заполненной очереди.",
+	}, func() float64 { return float64(dropped()) }))
+}
+
+// InstrumentDB подключает к GORM подсчёт ошибок запросов. Отсутствие записи
+// ошибкой не считается.
+func (m *Metrics) InstrumentDB(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		register  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, h := range hooks {
+		counter := m.dbErrors.WithLabelValues(h.operation)
+		err := h.register("metrics:"+h.operation, func(tx *gorm.DB) {
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				counter.Inc()
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheCollector читает счётчики кэша один раз на сбор метрик.
+type cacheCollector struct {
+	stats func() cache.Stats
+}
+
+var (
+	cacheRequestsDesc = prometheus.NewDesc(namespace+"_cache_requests_total",
+		"Обращения к кэшу ссылок: hit, negative_hit (входит в hit), miss.", []string{"result"}, nil)
+	cacheEvictionsDesc = prometheus.NewDesc(namespace+"_cache_evictions_total",
+		"Записи, вытесненные из локального кэша.", nil, nil)
+	cacheErrorsDesc = prometheus.NewDesc(namespace+"_cache_errors_total",
+		"Ошибки внешнего кэша, при которых запрос ушёл в БД.", nil, nil)
+	cacheEntriesDesc = prometheus.NewDesc(namespace+"_cache_entries",
+		"Число записей в локальном кэше.", nil, nil)
+)
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheRequestsDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheErrorsDesc
+	ch <- cacheEntriesDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(s.Hits), "hit")
+	ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(s.NegativeHits), "negative_hit")
+	ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(s.Misses), "miss")
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(s.Evictions))
+	ch <- prometheus.MustNewConstMetric(cacheErrorsDesc, prometheus.CounterValue, float64(s.Errors))
+	ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(s.Entries))
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"tinyurl/internal/dto"
+	"tinyurl/internal/service"
+)
+
+// Authenticator проверяет ключ API и возвращает его владельца.
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*service.Caller, error)
+}
+
+type callerKey struct{}
+
+// CallerFrom возвращает владельца ключа, с которым пришёл запрос.
+// Для анонимных запросов возвращает nil.
+func CallerFrom(ctx context.Context) *service.Caller {
+	caller, _ := ctx.Value(callerKey{}).(*service.Caller)
+	return caller
+}
+
+// Auth — middleware аутентификации по заголовку Authorization: Bearer <ключ>.
+// Владелец ключа сохраняется в контексте запроса (см. CallerFrom) и записывается
+// как инициатор изменений ссылок. Недействительный ключ всегда даёт 401;
+// запрос без ключа пропускается, только если required = false.
+func Auth(authn Authenticator, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := bearerToken(r)
+			if !ok {
+				if required {
+					writeUnauthorized(w, "требуется ключ api")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			caller, err := authn.Authenticate(r.Context(), key)
+			if err != nil {
+				if errors.Is(err, service.ErrInvalidAPIKey) {
+					writeUnauthorized(w, "недействительный ключ api")
+					return
+				}
+				slog.Error("ошибка проверки ключа api", "error", err)
+				writeError(w, http.StatusInternalServerError, "не удалось проверить ключ api")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), callerKey{}, caller)
+			ctx = service.WithActor(ctx, caller.Name)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// bearerToken извлекает ключ из заголовка Authorization.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func writeUnauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="tinyurl"`)
+	writeError(w, http.StatusUnauthorized, msg)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(dto.ErrorResponse{Error: msg})
+}
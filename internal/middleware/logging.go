@@ -4,6 +4,8 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // wrappedWriter — обёртка над ResponseWriter для перехвата статус-кода.
@@ -17,7 +19,8 @@ func (w *wrappedWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
-// Logging — middleware для логирования HTTP-запросов.
+// Logging — middleware для логирования HTTP-запросов. Должен стоять после Tracing,
+// чтобы в запись попали trace_id и span_id.
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -25,12 +28,18 @@ func Logging(next http.Handler) http.Handler {
 
 		next.ServeHTTP(ww, r)
 
-		slog.Info("запрос",
+		attrs := []any{
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", ww.statusCode,
 			"duration", time.Since(start).String(),
 			"remote", r.RemoteAddr,
-		)
+		}
+		// Идентификаторы трассы связывают строку лога со спанами запроса (см. Tracing)
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			attrs = append(attrs, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+		}
+
+		slog.Info("запрос", attrs...)
 	})
 }
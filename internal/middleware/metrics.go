@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RequestObserver — получатель метрик HTTP-запросов (реализуется internal/metrics).
+type RequestObserver interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+}
+
+// unmatchedRoute — метка запросов, не совпавших ни с одним маршрутом.
+const unmatchedRoute = "unmatched"
+
+// knownMethods — методы, которые попадают в метки как есть; остальные сводятся к OTHER.
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// Metrics — middleware, учитывающий число и длительность запросов. В метку route
+// пишется шаблон маршрута chi (например, /api/v1/urls/{code}), а не путь запроса:
+// иначе каждый короткий код порождал бы отдельный временной ряд.
+func Metrics(obs RequestObserver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := &wrappedWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(ww, r)
+
+			// Шаблон известен только после маршрутизации, поэтому читается после next
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+			method := r.Method
+			if !knownMethods[method] {
+				method = "OTHER"
+			}
+
+			obs.ObserveRequest(method, route, ww.statusCode, time.Since(start))
+		})
+	}
+}
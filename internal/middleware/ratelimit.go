@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tinyurl/internal/ratelimit"
+)
+
+// RateLimits — набор именованных политик ограничения частоты запросов.
+type RateLimits struct {
+	limiters map[string]*ratelimit.Limiter
+}
+
+// NewRateLimits создаёт ограничители для включённых политик.
+func NewRateLimits(policies map[string]ratelimit.Policy) *RateLimits {
+	limiters := make(map[string]*ratelimit.Limiter, len(policies))
+	for name, p := range policies {
+		if p.Enabled() {
+			limiters[name] = ratelimit.New(p)
+		}
+	}
+	return &RateLimits{limiters: limiters}
+}
+
+// For возвращает middleware для политики name. Если политика не задана
+// или выключена, запросы пропускаются без ограничений.
+func (rl *RateLimits) For(name string) func(http.Handler) http.Handler {
+	limiter, ok := rl.limiters[name]
+	if !ok {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return RateLimit(limiter)
+}
+
+// RateLimit — middleware ограничения частоты запросов. Клиент определяется
+// по ключу API (если Auth выполнен раньше), иначе по IP-адресу (см. RealIP).
+// Ответы содержат заголовки RateLimit-*, отказ — 429 с Retry-After.
+func RateLimit(limiter *ratelimit.Limiter) func(http.Handler) http.Handler {
+	p := limiter.Policy()
+	policy := strconv.Itoa(p.Requests) + ";w=" + strconv.Itoa(int(p.Period.Seconds())) +
+		";burst=" + strconv.Itoa(p.Capacity())
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := limiter.Allow(rateLimitKey(r))
+
+			h := w.Header()
+			h.Set("RateLimit-Policy", policy)
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(res.RetryAfter), 1)))
+				writeError(w, http.StatusTooManyRequests, "слишком много запросов, повторите позже")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey возвращает ключ клиента для ограничителя.
+func rateLimitKey(r *http.Request) string {
+	if caller := CallerFrom(r.Context()); caller != nil {
+		return "key:" + strconv.FormatInt(caller.KeyID, 10)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
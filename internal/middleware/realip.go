@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies разбирает список доверенных прокси: IP-адреса или подсети в нотации CIDR.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if strings.Contains(v, "/") {
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, fmt.Errorf("некорректная подсеть доверенного прокси %q: %w", v, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный адрес доверенного прокси %q: %w", v, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// RealIP — middleware, заменяющий r.RemoteAddr адресом клиента из X-Forwarded-For.
+// Заголовку верим, только если запрос пришёл от доверенного прокси: цепочка
+// разбирается справа налево, доверенные адреса пропускаются, и клиентом считается
+// первый недоверенный. Без доверенных прокси заголовок игнорируется.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := forwardedClient(r, trusted); ok {
+				r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient возвращает адрес клиента из X-Forwarded-For или false,
+// если заголовку нельзя верить.
+func forwardedClient(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	if len(trusted) == 0 {
+		return netip.Addr{}, false
+	}
+
+	peer, err := remoteAddr(r)
+	if err != nil || !isTrusted(peer, trusted) {
+		return netip.Addr{}, false
+	}
+
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	client := netip.Addr{}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Мусор в цепочке: дальше влево верить нельзя
+			break
+		}
+		client = addr.Unmap()
+		if !isTrusted(client, trusted) {
+			break
+		}
+	}
+
+	return client, client.IsValid()
+}
+
+func remoteAddr(r *http.Request) (netip.Addr, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var httpTracer = otel.Tracer("tinyurl/internal/middleware")
+
+// Tracing — middleware, открывающий серверный спан на каждый запрос. Контекст
+// родительской трассы читается из заголовков traceparent/tracestate (W3C Trace Context).
+// Спан называется по шаблону маршрута chi, а не по пути — как и метки в Metrics.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := httpTracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		ww := &wrappedWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				span.SetName(r.Method + " " + pattern)
+				span.SetAttributes(attribute.String("http.route", pattern))
+			}
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", ww.statusCode))
+		if ww.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, strconv.Itoa(ww.statusCode))
+		}
+	})
+}
@@ -0,0 +1,295 @@
+// Package migrate применяет версионированные SQL-миграции и ведёт их учёт
+// в таблице schema_migrations.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Dialect — диалект SQL базы, к которой применяются миграции.
+type Dialect string
+
+// Поддерживаемые диалекты.
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// advisoryLockID — ключ рекомендательной блокировки PostgreSQL ("tinyurl" в ASCII).
+// Пока одна реплика применяет миграции, остальные ждут на этой блокировке.
+const advisoryLockID int64 = 0x74696e7975726c
+
+// Migration — одна версия схемы: SQL для применения и для отката.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status — миграция и момент её применения (nil — ещё не применена).
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// fileRe — имя файла миграции: 001_init.up.sql, 001_init.down.sql.
+var fileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load читает миграции из корня fsys. У каждой версии должны быть оба файла: up и down.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("миграции: чтение каталога: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("миграции: чтение %s: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("миграции: у версии %d разные имена: %s и %s", version, mig.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("миграции: у версии %d нет файла up или down", mig.Version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Runner применяет и откатывает миграции.
+type Runner struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// New создаёт Runner для набора миграций, упорядоченного по версии (см. Load).
+func New(db *sql.DB, dialect Dialect, migrations []Migration) *Runner {
+	return &Runner{db: db, dialect: dialect, migrations: migrations}
+}
+
+// Up применяет все ещё не применённые миграции по возрастанию версии.
+// Каждая миграция выполняется в своей транзакции вместе с записью в schema_migrations.
+// Возвращает число применённых миграций.
+func (r *Runner) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := r.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range r.migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := r.apply(ctx, conn, m, true); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает steps последних применённых миграций. Возвращает число откаченных.
+func (r *Runner) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := r.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(r.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			m := r.migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if err := r.apply(ctx, conn, m, false); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status возвращает все известные миграции с отметкой о применении.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := r.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]Status, 0, len(r.migrations))
+		for _, m := range r.migrations {
+			s := Status{Migration: m}
+			if at, ok := done[m.Version]; ok {
+				s.AppliedAt = &at
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock выполняет fn на выделенном соединении. В PostgreSQL соединение
+// держит рекомендательную блокировку, поэтому реплики, стартующие одновременно,
+// применяют миграции по очереди. SQLite блокирует файл сам.
+func (r *Runner) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("миграции: получение соединения: %w", err)
+	}
+	defer conn.Close()
+
+	if r.dialect == Postgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+			return fmt.Errorf("миграции: захват блокировки: %w", err)
+		}
+		defer func() {
+			// Отдельный контекст: блокировку нужно снять и при отменённом ctx
+			unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", advisoryLockID); err != nil {
+				slog.Error("миграции: не удалось снять блокировку", "error", err)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, r.createTableSQL()); err != nil {
+		return fmt.Errorf("миграции: создание schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions возвращает применённые версии и время их применения.
+func (r *Runner) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("миграции: чтение schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version int64
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("миграции: чтение schema_migrations: %w", err)
+		}
+		done[version] = at
+	}
+	return done, rows.Err()
+}
+
+// apply применяет (up = true) или откатывает миграцию в транзакции.
+func (r *Runner) apply(ctx context.Context, conn *sql.Conn, m Migration, up bool) error {
+	direction, script := "up", m.Up
+	if !up {
+		direction, script = "down", m.Down
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("миграции: начало транзакции: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit откат ничего не делает
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("миграции: %03d_%s (%s): %w", m.Version, m.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, r.bind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			m.Version, m.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, r.bind("DELETE FROM schema_migrations WHERE version = ?"), m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("миграции: запись в schema_migrations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("миграции: фиксация %03d_%s: %w", m.Version, m.Name, err)
+	}
+
+	slog.Info("миграция выполнена", "version", m.Version, "name", m.Name, "direction", direction)
+	return nil
+}
+
+func (r *Runner) createTableSQL() string {
+	timeType := "TIMESTAMPTZ"
+	if r.dialect == SQLite {
+		timeType = "DATETIME"
+	}
+	return `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    BIGINT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    applied_at ` + timeType + ` NOT NULL
+)`
+}
+
+// bind заменяет плейсхолдеры ? на $1, $2, … для PostgreSQL.
+func (r *Runner) bind(query string) string {
+	if r.dialect != Postgres {
+		return query
+	}
+
+	out := make([]byte, 0, len(query)+8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			out = append(out, '$')
+			out = strconv.AppendInt(out, int64(n), 10)
+			continue
+		}
+		out = append(out, query[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// APIKey — модель таблицы api_keys: ключи доступа к API.
+// Сам ключ не хранится — только его SHA-256 и короткий префикс для опознания.
+type APIKey struct {
+	ID        int64      `gorm:"primaryKey" json:"id"`
+	Name      string     `gorm:"size:100;not null" json:"name"`
+	Prefix    string     `gorm:"size:16;not null" json:"prefix"`
+	KeyHash   string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// TableName возвращает имя таблицы в БД.
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsRevoked сообщает, отозван ли ключ.
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
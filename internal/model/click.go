@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Click — модель таблицы clicks: событие перехода по короткой ссылке.
+type Click struct {
+	ID        int64     `gorm:"primaryKey;index:idx_clicks_pending,where:NOT aggregated" json:"id"`
+	URLID     int64     `gorm:"not null;index:idx_clicks_url_time,priority:1" json:"url_id"`
+	ShortURL  string    `gorm:"size:12;not null" json:"short_url"`
+	ClickedAt time.Time `gorm:"not null;index:idx_clicks_url_time,priority:2" json:"clicked_at"`
+	Referrer  string    `gorm:"not null;default:''" json:"referrer"`
+	UserAgent string    `gorm:"not null;default:''" json:"user_agent"`
+	// IPHash — SHA-256 от соли и IP-адреса клиента; сам адрес не хранится.
+	IPHash    string `gorm:"size:64;not null;default:''" json:"ip_hash"`
+	RequestID string `gorm:"size:128;not null;default:''" json:"request_id"`
+	// Variant — вариант A/B-теста, на который попал посетитель; пустой — ссылка без теста.
+	Variant string `gorm:"size:64;not null;default:''" json:"variant,omitempty"`
+	// Country — страна посетителя по базе GeoIP (ISO 3166-1 alpha-2); пустая — неизвестна.
+	Country string `gorm:"size:2;not null;default:''" json:"country,omitempty"`
+	// Aggregated — событие уже учтено в агрегатах click_hourly и click_dimensions.
+	Aggregated bool `gorm:"not null;default:false" json:"-"`
+}
+
+// TableName возвращает имя таблицы в БД.
+func (Click) TableName() string {
+	return "clicks"
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Измерения, в разрезе которых агрегируются переходы (ClickDimension.Dimension).
+const (
+	DimensionReferrer = "referrer"
+	DimensionBrowser  = "browser"
+	DimensionOS       = "os"
+	DimensionDevice   = "device"
+	DimensionVariant  = "variant"
+	DimensionCountry  = "country"
+)
+
+// ClickHourly — модель таблицы click_hourly: число переходов по ссылке за час (UTC).
+type ClickHourly struct {
+	URLID  int64     `gorm:"primaryKey;autoIncrement:false" json:"url_id"`
+	Hour   time.Time `gorm:"primaryKey" json:"hour"`
+	Clicks int64     `gorm:"not null" json:"clicks"`
+}
+
+// TableName возвращает имя таблицы в БД.
+func (ClickHourly) TableName() string {
+	return "click_hourly"
+}
+
+// ClickDimension — модель таблицы click_dimensions: число переходов по ссылке за день (UTC)
+// с данным значением измерения (источник, браузер, ОС, класс устройства).
+type ClickDimension struct {
+	URLID     int64     `gorm:"primaryKey;autoIncrement:false" json:"url_id"`
+	Day       time.Time `gorm:"primaryKey" json:"day"`
+	Dimension string    `gorm:"primaryKey;size:16" json:"dimension"`
+	Value     string    `gorm:"primaryKey;size:255" json:"value"`
+	Clicks    int64     `gorm:"not null" json:"clicks"`
+}
+
+// TableName возвращает имя таблицы в БД.
+func (ClickDimension) TableName() string {
+	return "click_dimensions"
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"maps"
+	"time"
+)
+
+// Платформы для выбора оригинального URL по устройству посетителя (Targeting.Devices).
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformDesktop = "desktop"
+)
+
+// Targeting — правила выбора оригинального URL в зависимости от посетителя.
+// Хранится в колонке urls.targeting как JSON; если ни одно правило не подошло,
+// переход ведёт на URL.LongURL.
+type Targeting struct {
+	// Devices — оригинальный URL по платформе: ios, android, desktop.
+	Devices map[string]string `json:"devices,omitempty"`
+	// Countries — оригинальный URL по стране посетителя (ISO 3166-1 alpha-2, "DE").
+	Countries map[string]string `json:"countries,omitempty"`
+	// Schedule — оригинальный URL по времени перехода: действует первое окно,
+	// в которое попадает момент перехода.
+	Schedule []ScheduleWindow `json:"schedule,omitempty"`
+	// Variants — варианты A/B-теста; посетитель без подходящего правила Devices,
+	// Countries или Schedule попадает в один из них пропорционально весам.
+	Variants []Variant `json:"variants,omitempty"`
+}
+
+// ScheduleWindow — оригинальный URL, действующий в интервале [From, To).
+// Отсутствующая граница означает интервал без начала или без конца.
+type ScheduleWindow struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+	URL  string     `json:"url"`
+}
+
+// Contains сообщает, попадает ли момент now в окно.
+func (w ScheduleWindow) Contains(now time.Time) bool {
+	return (w.From == nil || !now.Before(*w.From)) && (w.To == nil || now.Before(*w.To))
+}
+
+// Variant — вариант A/B-теста: оригинальный URL с весом.
+type Variant struct {
+	// Name — имя варианта, записывается в события переходов (Click.Variant).
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// IsEmpty сообщает, что правил нет.
+func (t *Targeting) IsEmpty() bool {
+	return t == nil || len(t.Schedule) == 0 && !t.DependsOnVisitor()
+}
+
+// DependsOnVisitor сообщает, что выбор URL зависит от посетителя: устройства,
+// страны или варианта A/B-теста (расписание от посетителя не зависит).
+func (t *Targeting) DependsOnVisitor() bool {
+	return t != nil && (len(t.Devices) > 0 || len(t.Countries) > 0 || len(t.Variants) > 0)
+}
+
+// Clone возвращает независимую копию правил.
+func (t *Targeting) Clone() *Targeting {
+	if t == nil {
+		return nil
+	}
+	cp := &Targeting{
+		Devices:   maps.Clone(t.Devices),
+		Countries: maps.Clone(t.Countries),
+	}
+	if t.Schedule != nil {
+		cp.Schedule = append([]ScheduleWindow(nil), t.Schedule...)
+	}
+	if t.Variants != nil {
+		cp.Variants = append([]Variant(nil), t.Variants...)
+	}
+	return cp
+}
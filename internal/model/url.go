@@ -4,13 +4,43 @@ import "time"
 
 // URL — модель таблицы urls в базе данных.
 type URL struct {
-	ID        int64     `gorm:"primaryKey;autoIncrement:false" json:"id"`
-	ShortURL  string    `gorm:"uniqueIndex;size:12;not null" json:"short_url"`
-	LongURL   string    `gorm:"not null;index:idx_long_url" json:"long_url"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID        int64      `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	ShortURL  string     `gorm:"uniqueIndex;size:12;not null" json:"short_url"`
+	LongURL   string     `gorm:"not null" json:"long_url"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt *time.Time `gorm:"index:idx_urls_expires_at" json:"expires_at,omitempty"`
+	// MaxClicks — лимит переходов (nil — без ограничений), ClickCount — засчитанные переходы.
+	MaxClicks  *int64 `json:"max_clicks,omitempty"`
+	ClickCount int64  `gorm:"not null;default:0" json:"click_count"`
+	// PasswordHash — bcrypt-хеш пароля; пустая строка — ссылка без пароля.
+	PasswordHash string `gorm:"size:60;not null;default:''" json:"-"`
+	// Disabled — ссылка отключена вручную и не разрешается.
+	Disabled bool `gorm:"not null;default:false" json:"disabled"`
+	// RedirectType — HTTP-статус редиректа: 301, 302, 307 или 308.
+	RedirectType int `gorm:"not null;default:302" json:"redirect_type"`
+	// NotBefore и NotAfter — окно активности: до NotBefore ссылка ещё не работает,
+	// с NotAfter — уже не работает; как и ссылки с истёкшим ExpiresAt, такие записи удаляет очистка.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+	// Targeting — альтернативные оригинальные URL для разных посетителей; nil — нет правил.
+	Targeting *Targeting `gorm:"serializer:json" json:"targeting,omitempty"`
 }
 
 // TableName возвращает имя таблицы в БД.
 func (URL) TableName() string {
 	return "urls"
 }
+
+// IsExpired сообщает, истёк ли срок действия ссылки к моменту now.
+// Ссылка без ExpiresAt бессрочная.
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// IsPlain сообщает, что ссылка включена и у неё нет ограничений (срока действия,
+// окна активности, лимита переходов, пароля) и правил выбора URL, поэтому её можно
+// переиспользовать при дедупликации.
+func (u *URL) IsPlain() bool {
+	return !u.Disabled && u.ExpiresAt == nil && u.NotBefore == nil && u.NotAfter == nil &&
+		u.MaxClicks == nil && u.PasswordHash == "" && u.Targeting == nil
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// URLHistory — модель таблицы url_history: изменения оригинального URL ссылки.
+type URLHistory struct {
+	ID         int64     `gorm:"primaryKey" json:"id"`
+	URLID      int64     `gorm:"not null;uniqueIndex:idx_url_history_version" json:"url_id"`
+	Version    int       `gorm:"not null;uniqueIndex:idx_url_history_version" json:"version"`
+	OldLongURL string    `gorm:"not null" json:"old_long_url"`
+	NewLongURL string    `gorm:"not null" json:"new_long_url"`
+	ChangedBy  string    `gorm:"size:255;not null" json:"changed_by"`
+	ChangedAt  time.Time `gorm:"autoCreateTime" json:"changed_at"`
+
+	// URL нужен только для внешнего ключа: история удаляется вместе со ссылкой.
+	URL *URL `gorm:"foreignKey:URLID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName возвращает имя таблицы в БД.
+func (URLHistory) TableName() string {
+	return "url_history"
+}
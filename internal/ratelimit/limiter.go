@@ -0,0 +1,133 @@
+// Package ratelimit реализует ограничение частоты запросов алгоритмом token bucket.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто удалять из памяти корзины неактивных клиентов.
+const sweepInterval = time.Minute
+
+// Policy — политика ограничения: Requests запросов за Period с запасом Burst.
+type Policy struct {
+	Requests int
+	Period   time.Duration
+	// Burst — ёмкость корзины; если 0, равна Requests.
+	Burst int
+}
+
+// Enabled сообщает, задана ли политика.
+func (p Policy) Enabled() bool {
+	return p.Requests > 0 && p.Period > 0
+}
+
+// Capacity возвращает ёмкость корзины.
+func (p Policy) Capacity() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+	return p.Requests
+}
+
+// Result — результат проверки запроса.
+type Result struct {
+	Allowed bool
+	// Limit — ёмкость корзины.
+	Limit int
+	// Remaining — сколько запросов ещё можно сделать без ожидания.
+	Remaining int
+	// Reset — через сколько корзина наполнится полностью.
+	Reset time.Duration
+	// RetryAfter — через сколько появится следующий токен (только если Allowed = false).
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter — набор корзин по ключам клиентов для одной политики. Безопасен
+// для конкурентного использования; состояние хранится в памяти процесса.
+type Limiter struct {
+	policy Policy
+	rate   float64 // токенов в секунду
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New создаёт ограничитель для политики. Политика должна быть включена (см. Policy.Enabled).
+func New(policy Policy) *Limiter {
+	return NewWithClock(policy, time.Now)
+}
+
+// NewWithClock создаёт ограничитель с заданным источником времени (для тестов).
+func NewWithClock(policy Policy, now func() time.Time) *Limiter {
+	return &Limiter{
+		policy:    policy,
+		rate:      float64(policy.Requests) / policy.Period.Seconds(),
+		now:       now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: now(),
+	}
+}
+
+// Policy возвращает политику ограничителя.
+func (l *Limiter) Policy() Policy {
+	return l.policy
+}
+
+// Allow списывает токен из корзины клиента key, если он есть.
+func (l *Limiter) Allow(key string) Result {
+	capacity := float64(l.policy.Capacity())
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now, capacity)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	}
+
+	res := Result{Limit: l.policy.Capacity()}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.duration(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = l.duration(capacity - b.tokens)
+
+	return res
+}
+
+// sweep удаляет корзины, которые уже успели наполниться: для них
+// новая корзина ничем не отличается от сохранённой.
+func (l *Limiter) sweep(now time.Time, capacity float64) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= capacity {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// duration возвращает время накопления tokens токенов.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tinyurl/internal/model"
+)
+
+// APIKeyRepository — репозиторий для работы с таблицей api_keys.
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository создаёт новый экземпляр репозитория ключей API.
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create сохраняет новый ключ.
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		return fmt.Errorf("репозиторий: создание ключа api: %w", err)
+	}
+	return nil
+}
+
+// FindActiveByHash ищет неотозванный ключ по хешу. Возвращает (nil, nil), если ключ не найден.
+func (r *APIKeyRepository) FindActiveByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	var key model.APIKey
+	result := r.db.WithContext(ctx).
+		Where("key_hash = ? AND revoked_at IS NULL", hash).
+		First(&key)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("репозиторий: поиск ключа api: %w", result.Error)
+	}
+	return &key, nil
+}
+
+// List возвращает все ключи, включая отозванные, в порядке создания.
+func (r *APIKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	if err := r.db.WithContext(ctx).Order("id").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("репозиторий: список ключей api: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke отзывает ключ. Возвращает false, если ключ не найден или уже отозван.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int64, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", now.UTC())
+	if result.Error != nil {
+		return false, fmt.Errorf("репозиторий: отзыв ключа api: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
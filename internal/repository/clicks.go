@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"tinyurl/internal/model"
+)
+
+// ClickRepository — репозиторий для записи событий переходов в таблицу clicks.
+type ClickRepository struct {
+	db *gorm.DB
+}
+
+// NewClickRepository создаёт новый экземпляр репозитория переходов.
+func NewClickRepository(db *gorm.DB) *ClickRepository {
+	return &ClickRepository{db: db}
+}
+
+// SaveBatch сохраняет пачку событий переходов и увеличивает счётчики click_count
+// у соответствующих ссылок на counts[url_id] в одной транзакции. Счётчики
+// обновляются в порядке возрастания url_id.
+func (r *ClickRepository) SaveBatch(ctx context.Context, clicks []model.Click, counts map[int64]int64) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(clicks) > 0 {
+			if err := tx.Create(&clicks).Error; err != nil {
+				return err
+			}
+		}
+
+		// Строки urls блокируются в порядке id: иначе две реплики, записывающие пачки
+		// с общими ссылками, могут захватить их в разном порядке и взаимно заблокироваться.
+		for _, urlID := range slices.Sorted(maps.Keys(counts)) {
+			if err := tx.Model(&model.URL{}).
+				Where("id = ?", urlID).
+				UpdateColumn("click_count", gorm.Expr("click_count + ?", counts[urlID])).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("репозиторий: сохранение переходов: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,448 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"tinyurl/internal/model"
+)
+
+// memoryDB — общее состояние хранилищ в памяти. Все хранилища одного бэкенда
+// работают с ним под одной блокировкой, поэтому операции, которые в БД идут
+// в транзакции (изменение с записью истории, пачка переходов со счётчиками),
+// здесь тоже атомарны. Наружу отдаются только копии записей.
+type memoryDB struct {
+	mu sync.RWMutex
+
+	urls    map[string]*model.URL // по short_url
+	urlByID map[int64]*model.URL
+	history map[int64][]model.URLHistory // по url_id, по возрастанию версии
+
+	clicks     []model.Click
+	hourly     map[hourlyKey]int64
+	dimensions map[dimensionKey]int64
+
+	apiKeys []*model.APIKey
+
+	seq int64 // счётчик для ID, которые в БД выдаёт последовательность
+}
+
+type hourlyKey struct {
+	urlID int64
+	hour  time.Time
+}
+
+type dimensionKey struct {
+	urlID     int64
+	day       time.Time
+	dimension string
+	value     string
+}
+
+// NewMemoryStorage создаёт хранилища в памяти процесса. Данные не переживают
+// перезапуск; бэкенд предназначен для тестов и локального запуска без БД.
+func NewMemoryStorage() *Storage {
+	db := &memoryDB{
+		urls:       make(map[string]*model.URL),
+		urlByID:    make(map[int64]*model.URL),
+		history:    make(map[int64][]model.URLHistory),
+		hourly:     make(map[hourlyKey]int64),
+		dimensions: make(map[dimensionKey]int64),
+	}
+	return &Storage{
+		URLs:    &memoryURLs{db: db},
+		Clicks:  &memoryClicks{db: db},
+		Stats:   &memoryStats{db: db},
+		APIKeys: &memoryAPIKeys{db: db},
+	}
+}
+
+func (db *memoryDB) nextID() int64 {
+	db.seq++
+	return db.seq
+}
+
+// cloneURL копирует ссылку вместе со значениями под указателями.
+func cloneURL(u *model.URL) *model.URL {
+	cp := *u
+	if u.ExpiresAt != nil {
+		t := *u.ExpiresAt
+		cp.ExpiresAt = &t
+	}
+	if u.MaxClicks != nil {
+		n := *u.MaxClicks
+		cp.MaxClicks = &n
+	}
+	if u.NotBefore != nil {
+		t := *u.NotBefore
+		cp.NotBefore = &t
+	}
+	if u.NotAfter != nil {
+		t := *u.NotAfter
+		cp.NotAfter = &t
+	}
+	cp.Targeting = u.Targeting.Clone()
+	return &cp
+}
+
+// --- ссылки ---
+
+type memoryURLs struct {
+	db *memoryDB
+}
+
+func (r *memoryURLs) Create(_ context.Context, url *model.URL) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.urls[url.ShortURL]; ok {
+		return ErrDuplicate
+	}
+	if url.ID == 0 {
+		url.ID = r.db.nextID()
+	}
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now()
+	}
+
+	stored := cloneURL(url)
+	r.db.urls[url.ShortURL] = stored
+	r.db.urlByID[url.ID] = stored
+	return nil
+}
+
+func (r *memoryURLs) FindByShortURL(_ context.Context, shortURL string) (*model.URL, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	url, ok := r.db.urls[shortURL]
+	if !ok {
+		return nil, nil
+	}
+	return cloneURL(url), nil
+}
+
+func (r *memoryURLs) FindByLongURL(_ context.Context, longURL string, redirectType int) (*model.URL, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	// Как и в БД, из нескольких подходящих берётся ссылка с наименьшим ID
+	var found *model.URL
+	for _, url := range r.db.urls {
+		if url.LongURL == longURL && url.RedirectType == redirectType && url.IsPlain() &&
+			(found == nil || url.ID < found.ID) {
+			found = url
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	return cloneURL(found), nil
+}
+
+func (r *memoryURLs) ConsumeClick(_ context.Context, id int64) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	url, ok := r.db.urlByID[id]
+	if !ok || (url.MaxClicks != nil && url.ClickCount >= *url.MaxClicks) {
+		return false, nil
+	}
+	url.ClickCount++
+	return true, nil
+}
+
+func (r *memoryURLs) UpdateLongURL(_ context.Context, shortURL, longURL, changedBy string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	url, ok := r.db.urls[shortURL]
+	if !ok {
+		return false, nil
+	}
+	if url.LongURL == longURL {
+		return true, nil
+	}
+
+	history := r.db.history[url.ID]
+	r.db.history[url.ID] = append(history, model.URLHistory{
+		ID:         r.db.nextID(),
+		URLID:      url.ID,
+		Version:    len(history) + 1,
+		OldLongURL: url.LongURL,
+		NewLongURL: longURL,
+		ChangedBy:  changedBy,
+		ChangedAt:  time.Now(),
+	})
+	url.LongURL = longURL
+	return true, nil
+}
+
+func (r *memoryURLs) FindHistory(_ context.Context, urlID int64) ([]model.URLHistory, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return slices.Clone(r.db.history[urlID]), nil
+}
+
+func (r *memoryURLs) FindHistoryVersion(_ context.Context, urlID int64, version int) (*model.URLHistory, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, h := range r.db.history[urlID] {
+		if h.Version == version {
+			return &h, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryURLs) SetDisabled(_ context.Context, shortURL string, disabled bool) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	url, ok := r.db.urls[shortURL]
+	if !ok {
+		return false, nil
+	}
+	url.Disabled = disabled
+	return true, nil
+}
+
+func (r *memoryURLs) FindEnabled(_ context.Context, afterID int64, limit int) ([]model.URL, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var urls []model.URL
+	for _, url := range r.db.urls {
+		if !url.Disabled && url.ID > afterID {
+			urls = append(urls, *cloneURL(url))
+		}
+	}
+	slices.SortFunc(urls, func(a, b model.URL) int { return cmp.Compare(a.ID, b.ID) })
+	if limit > 0 && len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
+
+func (r *memoryURLs) DeleteByShortURL(_ context.Context, shortURL string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	url, ok := r.db.urls[shortURL]
+	if !ok {
+		return false, nil
+	}
+	r.db.deleteURL(url)
+	return true, nil
+}
+
+func (r *memoryURLs) DeleteExpired(_ context.Context, now time.Time, limit int) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var expired []*model.URL
+	for _, url := range r.db.urls {
+		if url.IsExpired(now) || url.NotAfter != nil && !url.NotAfter.After(now) {
+			expired = append(expired, url)
+		}
+	}
+	slices.SortFunc(expired, func(a, b *model.URL) int { return cmp.Compare(a.ID, b.ID) })
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	for _, url := range expired {
+		r.db.deleteURL(url)
+	}
+	return int64(len(expired)), nil
+}
+
+func (r *memoryURLs) Ping(context.Context) error {
+	return nil
+}
+
+// deleteURL удаляет ссылку и её историю (как ON DELETE CASCADE в БД).
+// События переходов и агрегаты остаются, как и в БД.
+func (db *memoryDB) deleteURL(url *model.URL) {
+	delete(db.urls, url.ShortURL)
+	delete(db.urlByID, url.ID)
+	delete(db.history, url.ID)
+}
+
+// --- переходы ---
+
+type memoryClicks struct {
+	db *memoryDB
+}
+
+func (r *memoryClicks) SaveBatch(_ context.Context, clicks []model.Click, counts map[int64]int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, c := range clicks {
+		c.ID = r.db.nextID()
+		r.db.clicks = append(r.db.clicks, c)
+	}
+	for urlID, n := range counts {
+		if url, ok := r.db.urlByID[urlID]; ok {
+			url.ClickCount += n
+		}
+	}
+	return nil
+}
+
+// --- статистика ---
+
+type memoryStats struct {
+	db *memoryDB
+}
+
+func (r *memoryStats) AggregatePending(_ context.Context, limit int, summarize Summarizer) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var idx []int
+	for i := range r.db.clicks {
+		if len(idx) == limit {
+			break
+		}
+		if !r.db.clicks[i].Aggregated {
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) == 0 {
+		return 0, nil
+	}
+
+	pending := make([]model.Click, 0, len(idx))
+	for _, i := range idx {
+		pending = append(pending, r.db.clicks[i])
+	}
+
+	hourly, dims := summarize(pending)
+	for _, h := range hourly {
+		r.db.hourly[hourlyKey{urlID: h.URLID, hour: h.Hour}] += h.Clicks
+	}
+	for _, d := range dims {
+		r.db.dimensions[dimensionKey{urlID: d.URLID, day: d.Day, dimension: d.Dimension, value: d.Value}] += d.Clicks
+	}
+	for _, i := range idx {
+		r.db.clicks[i].Aggregated = true
+	}
+
+	return len(idx), nil
+}
+
+func (r *memoryStats) FindHourly(_ context.Context, urlID int64, from, to time.Time) ([]model.ClickHourly, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var rows []model.ClickHourly
+	for k, clicks := range r.db.hourly {
+		if k.urlID == urlID && !k.hour.Before(from) && k.hour.Before(to) {
+			rows = append(rows, model.ClickHourly{URLID: k.urlID, Hour: k.hour, Clicks: clicks})
+		}
+	}
+	slices.SortFunc(rows, func(a, b model.ClickHourly) int { return a.Hour.Compare(b.Hour) })
+	return rows, nil
+}
+
+func (r *memoryStats) TopDimension(
+	_ context.Context,
+	urlID int64,
+	dimension string,
+	fromDay, toDay time.Time,
+	limit int,
+) ([]DimensionCount, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	sums := make(map[string]int64)
+	for k, clicks := range r.db.dimensions {
+		if k.urlID == urlID && k.dimension == dimension && !k.day.Before(fromDay) && !k.day.After(toDay) {
+			sums[k.value] += clicks
+		}
+	}
+
+	rows := make([]DimensionCount, 0, len(sums))
+	for value, clicks := range sums {
+		rows = append(rows, DimensionCount{Value: value, Clicks: clicks})
+	}
+	slices.SortFunc(rows, func(a, b DimensionCount) int {
+		if c := cmp.Compare(b.Clicks, a.Clicks); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// --- ключи API ---
+
+type memoryAPIKeys struct {
+	db *memoryDB
+}
+
+func (r *memoryAPIKeys) Create(_ context.Context, key *model.APIKey) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, k := range r.db.apiKeys {
+		if k.KeyHash == key.KeyHash {
+			return ErrDuplicate
+		}
+	}
+	key.ID = r.db.nextID()
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	stored := *key
+	r.db.apiKeys = append(r.db.apiKeys, &stored)
+	return nil
+}
+
+func (r *memoryAPIKeys) FindActiveByHash(_ context.Context, hash string) (*model.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, k := range r.db.apiKeys {
+		if k.KeyHash == hash && !k.IsRevoked() {
+			cp := *k
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryAPIKeys) List(context.Context) ([]model.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	keys := make([]model.APIKey, 0, len(r.db.apiKeys))
+	for _, k := range r.db.apiKeys {
+		keys = append(keys, *k)
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeys) Revoke(_ context.Context, id int64, now time.Time) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, k := range r.db.apiKeys {
+		if k.ID == id && !k.IsRevoked() {
+			k.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
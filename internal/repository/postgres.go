@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"tinyurl/internal/model"
 )
@@ -20,10 +22,22 @@ func NewURLRepository(db *gorm.DB) *URLRepository {
 	return &URLRepository{db: db}
 }
 
+// ErrDuplicate — запись с таким уникальным ключом (например, short_url) уже существует.
+var ErrDuplicate = errors.New("репозиторий: запись уже существует")
+
 // Create сохраняет новую запись URL в базу данных.
+// Если короткий код уже занят, возвращает ErrDuplicate.
 func (r *URLRepository) Create(ctx context.Context, url *model.URL) error {
+	// Время хранится в UTC: SQLite сравнивает метки времени как строки
+	if url.ExpiresAt != nil {
+		utc := url.ExpiresAt.UTC()
+		url.ExpiresAt = &utc
+	}
 	result := r.db.WithContext(ctx).Create(url)
 	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return ErrDuplicate
+		}
 		return fmt.Errorf("репозиторий: создание url: %w", result.Error)
 	}
 	return nil
@@ -43,9 +57,15 @@ func (r *URLRepository) FindByShortURL(ctx context.Context, shortURL string) (*m
 }
 
 // FindByLongURL ищет запись по оригинальному URL (для дедупликации).
-func (r *URLRepository) FindByLongURL(ctx context.Context, longURL string) (*model.URL, error) {
+// Учитываются только включённые ссылки без ограничений: бессрочные, без окна активности,
+// лимита переходов, пароля и правил выбора URL — и только с тем же типом редиректа.
+func (r *URLRepository) FindByLongURL(ctx context.Context, longURL string, redirectType int) (*model.URL, error) {
 	var url model.URL
-	result := r.db.WithContext(ctx).Where("long_url = ?", longURL).First(&url)
+	result := r.db.WithContext(ctx).
+		Where("long_url = ? AND redirect_type = ?", longURL, redirectType).
+		Where("NOT disabled AND expires_at IS NULL AND max_clicks IS NULL AND password_hash = ''").
+		Where("not_before IS NULL AND not_after IS NULL AND targeting IS NULL").
+		First(&url)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -55,6 +75,149 @@ func (r *URLRepository) FindByLongURL(ctx context.Context, longURL string) (*mod
 	return &url, nil
 }
 
+// ConsumeClick атомарно засчитывает переход по ссылке.
+// Возвращает false, если лимит уже исчерпан. Проверка и инкремент выполняются
+// одним UPDATE, поэтому конкурентные запросы не могут превысить лимит.
+func (r *URLRepository) ConsumeClick(ctx context.Context, id int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.URL{}).
+		Where("id = ?", id).
+		Where("max_clicks IS NULL OR click_count < max_clicks").
+		UpdateColumn("click_count", gorm.Expr("click_count + 1"))
+	if result.Error != nil {
+		return false, fmt.Errorf("репозиторий: учёт перехода: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UpdateLongURL меняет оригинальный URL ссылки и в той же транзакции записывает
+// изменение в url_history со следующим номером версии. Строка ссылки блокируется,
+// поэтому параллельные изменения получают последовательные версии.
+// Возвращает false, если код не найден.
+func (r *URLRepository) UpdateLongURL(ctx context.Context, shortURL, longURL, changedBy string) (bool, error) {
+	found := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var url model.URL
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("short_url = ?", shortURL).
+			First(&url)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return result.Error
+		}
+		found = true
+
+		if url.LongURL == longURL {
+			return nil
+		}
+
+		var version int
+		if err := tx.Model(&model.URLHistory{}).
+			Where("url_id = ?", url.ID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&version).Error; err != nil {
+			return err
+		}
+
+		// Update записывает новое значение и в url, поэтому старое сохраняем заранее
+		oldLongURL := url.LongURL
+		if err := tx.Model(&url).Update("long_url", longURL).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&model.URLHistory{
+			URLID:      url.ID,
+			Version:    version + 1,
+			OldLongURL: oldLongURL,
+			NewLongURL: longURL,
+			ChangedBy:  changedBy,
+		}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("репозиторий: изменение длинного url: %w", err)
+	}
+	return found, nil
+}
+
+// FindHistory возвращает изменения оригинального URL ссылки по возрастанию версии.
+func (r *URLRepository) FindHistory(ctx context.Context, urlID int64) ([]model.URLHistory, error) {
+	var history []model.URLHistory
+	result := r.db.WithContext(ctx).Where("url_id = ?", urlID).Order("version").Find(&history)
+	if result.Error != nil {
+		return nil, fmt.Errorf("репозиторий: получение истории url: %w", result.Error)
+	}
+	return history, nil
+}
+
+// FindHistoryVersion ищет изменение ссылки с указанной версией.
+func (r *URLRepository) FindHistoryVersion(ctx context.Context, urlID int64, version int) (*model.URLHistory, error) {
+	var entry model.URLHistory
+	result := r.db.WithContext(ctx).Where("url_id = ? AND version = ?", urlID, version).First(&entry)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("репозиторий: поиск версии url: %w", result.Error)
+	}
+	return &entry, nil
+}
+
+// SetDisabled отключает или включает ссылку. Возвращает false, если код не найден.
+func (r *URLRepository) SetDisabled(ctx context.Context, shortURL string, disabled bool) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.URL{}).
+		Where("short_url = ?", shortURL).
+		Update("disabled", disabled)
+	if result.Error != nil {
+		return false, fmt.Errorf("репозиторий: изменение статуса url: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FindEnabled возвращает не более limit включённых ссылок с ID больше afterID.
+func (r *URLRepository) FindEnabled(ctx context.Context, afterID int64, limit int) ([]model.URL, error) {
+	var urls []model.URL
+	result := r.db.WithContext(ctx).
+		Where("NOT disabled AND id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&urls)
+	if result.Error != nil {
+		return nil, fmt.Errorf("репозиторий: поиск включённых url: %w", result.Error)
+	}
+	return urls, nil
+}
+
+// DeleteByShortURL удаляет ссылку по коду. Возвращает false, если код не найден.
+func (r *URLRepository) DeleteByShortURL(ctx context.Context, shortURL string) (bool, error) {
+	result := r.db.WithContext(ctx).Where("short_url = ?", shortURL).Delete(&model.URL{})
+	if result.Error != nil {
+		return false, fmt.Errorf("репозиторий: удаление url: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteExpired удаляет не более limit ссылок, срок действия или окно активности которых
+// закончились к моменту now, и возвращает число удалённых записей.
+func (r *URLRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) (int64, error) {
+	// Подзапрос строится от того же сеанса, что и удаление, чтобы отмена ctx прерывала весь запрос
+	db := r.db.WithContext(ctx)
+	expired := db.Model(&model.URL{}).
+		Select("id").
+		Where("expires_at IS NOT NULL AND expires_at <= ?", now.UTC()).
+		Or("not_after IS NOT NULL AND not_after <= ?", now.UTC()).
+		Order("id").
+		Limit(limit)
+
+	result := db.Where("id IN (?)", expired).Delete(&model.URL{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("репозиторий: удаление истёкших url: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 // Ping проверяет доступность базы данных.
 func (r *URLRepository) Ping(ctx context.Context) error {
 	sqlDB, err := r.db.DB()
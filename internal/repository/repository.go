@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tinyurl/internal/model"
+)
+
+// URLStore — хранилище коротких ссылок и истории их изменений.
+// Методы поиска возвращают (nil, nil), если запись не найдена.
+type URLStore interface {
+	// Create сохраняет ссылку; занятый код даёт ErrDuplicate.
+	Create(ctx context.Context, url *model.URL) error
+	FindByShortURL(ctx context.Context, shortURL string) (*model.URL, error)
+	// FindByLongURL ищет включённую ссылку без ограничений (см. model.URL.IsPlain)
+	// с указанным типом редиректа.
+	FindByLongURL(ctx context.Context, longURL string, redirectType int) (*model.URL, error)
+	// ConsumeClick атомарно засчитывает переход, если лимит переходов не исчерпан.
+	ConsumeClick(ctx context.Context, id int64) (bool, error)
+	// UpdateLongURL меняет оригинальный URL и записывает изменение в историю.
+	UpdateLongURL(ctx context.Context, shortURL, longURL, changedBy string) (bool, error)
+	FindHistory(ctx context.Context, urlID int64) ([]model.URLHistory, error)
+	FindHistoryVersion(ctx context.Context, urlID int64, version int) (*model.URLHistory, error)
+	SetDisabled(ctx context.Context, shortURL string, disabled bool) (bool, error)
+	// FindEnabled возвращает не более limit включённых ссылок с ID больше afterID
+	// по возрастанию ID — для постраничного обхода всех ссылок.
+	FindEnabled(ctx context.Context, afterID int64, limit int) ([]model.URL, error)
+	// DeleteByShortURL удаляет ссылку вместе с историей изменений.
+	DeleteByShortURL(ctx context.Context, shortURL string) (bool, error)
+	DeleteExpired(ctx context.Context, now time.Time, limit int) (int64, error)
+	Ping(ctx context.Context) error
+}
+
+// ClickStore — хранилище событий переходов.
+type ClickStore interface {
+	// SaveBatch сохраняет события и увеличивает click_count ссылок на counts[url_id].
+	SaveBatch(ctx context.Context, clicks []model.Click, counts map[int64]int64) error
+}
+
+// StatsStore — хранилище агрегатов статистики переходов.
+type StatsStore interface {
+	AggregatePending(ctx context.Context, limit int, summarize Summarizer) (int, error)
+	FindHourly(ctx context.Context, urlID int64, from, to time.Time) ([]model.ClickHourly, error)
+	TopDimension(ctx context.Context, urlID int64, dimension string, fromDay, toDay time.Time, limit int) ([]DimensionCount, error)
+}
+
+// APIKeyStore — хранилище ключей API.
+type APIKeyStore interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	FindActiveByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	List(ctx context.Context) ([]model.APIKey, error)
+	Revoke(ctx context.Context, id int64, now time.Time) (bool, error)
+}
+
+// Storage — набор хранилищ одного бэкенда.
+type Storage struct {
+	URLs    URLStore
+	Clicks  ClickStore
+	Stats   StatsStore
+	APIKeys APIKeyStore
+}
+
+// NewGormStorage создаёт хранилища поверх подключения GORM к PostgreSQL или SQLite.
+func NewGormStorage(db *gorm.DB) *Storage {
+	return &Storage{
+		URLs:    NewURLRepository(db),
+		Clicks:  NewClickRepository(db),
+		Stats:   NewStatsRepository(db),
+		APIKeys: NewAPIKeyRepository(db),
+	}
+}
+
+// Проверка соответствия реализаций интерфейсам на этапе компиляции.
+var (
+	_ URLStore    = (*URLRepository)(nil)
+	_ ClickStore  = (*ClickRepository)(nil)
+	_ StatsStore  = (*StatsRepository)(nil)
+	_ APIKeyStore = (*APIKeyRepository)(nil)
+)
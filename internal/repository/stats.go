@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"tinyurl/internal/model"
+)
+
+// StatsRepository — репозиторий агрегатов переходов (click_hourly, click_dimensions).
+type StatsRepository struct {
+	db *gorm.DB
+}
+
+// NewStatsRepository создаёт новый экземпляр репозитория статистики.
+func NewStatsRepository(db *gorm.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// Summarizer сворачивает пачку событий переходов в приращения агрегатов.
+type Summarizer func(clicks []model.Click) ([]model.ClickHourly, []model.ClickDimension)
+
+// AggregatePending забирает до limit ещё не агрегированных событий, прибавляет
+// их к агрегатам и помечает события учтёнными — всё в одной транзакции.
+// Строки блокируются с SKIP LOCKED, поэтому несколько реплик могут агрегировать
+// параллельно, не учитывая одно событие дважды. Возвращает число обработанных событий.
+func (r *StatsRepository) AggregatePending(ctx context.Context, limit int, summarize Summarizer) (int, error) {
+	var processed int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var clicks []model.Click
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("NOT aggregated").
+			Order("id").
+			Limit(limit).
+			Find(&clicks).Error; err != nil {
+			return err
+		}
+		if len(clicks) == 0 {
+			return nil
+		}
+
+		hourly, dims := summarize(clicks)
+
+		if len(hourly) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "url_id"}, {Name: "hour"}},
+				DoUpdates: clause.Assignments(map[string]any{"clicks": gorm.Expr("click_hourly.clicks + excluded.clicks")}),
+			}).Create(&hourly).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(dims) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{
+					{Name: "url_id"}, {Name: "day"}, {Name: "dimension"}, {Name: "value"},
+				},
+				DoUpdates: clause.Assignments(map[string]any{"clicks": gorm.Expr("click_dimensions.clicks + excluded.clicks")}),
+			}).Create(&dims).Error; err != nil {
+				return err
+			}
+		}
+
+		ids := make([]int64, 0, len(clicks))
+		for _, c := range clicks {
+			ids = append(ids, c.ID)
+		}
+		if err := tx.Model(&model.Click{}).Where("id IN ?", ids).Update("aggregated", true).Error; err != nil {
+			return err
+		}
+
+		processed = len(clicks)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("репозиторий: агрегация переходов: %w", err)
+	}
+	return processed, nil
+}
+
+// FindHourly возвращает почасовые агрегаты ссылки в интервале [from, to) по возрастанию часа.
+func (r *StatsRepository) FindHourly(ctx context.Context, urlID int64, from, to time.Time) ([]model.ClickHourly, error) {
+	var rows []model.ClickHourly
+	result := r.db.WithContext(ctx).
+		Where("url_id = ? AND hour >= ? AND hour < ?", urlID, from.UTC(), to.UTC()).
+		Order("hour").
+		Find(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("репозиторий: получение почасовой статистики: %w", result.Error)
+	}
+	return rows, nil
+}
+
+// DimensionCount — число переходов с данным значением измерения.
+type DimensionCount struct {
+	Value  string
+	Clicks int64
+}
+
+// TopDimension возвращает limit самых частых значений измерения за дни [fromDay, toDay].
+func (r *StatsRepository) TopDimension(
+	ctx context.Context,
+	urlID int64,
+	dimension string,
+	fromDay, toDay time.Time,
+	limit int,
+) ([]DimensionCount, error) {
+	var rows []DimensionCount
+	result := r.db.WithContext(ctx).
+		Model(&model.ClickDimension{}).
+		Select("value, SUM(clicks) AS clicks").
+		Where("url_id = ? AND dimension = ? AND day >= ? AND day <= ?", urlID, dimension, fromDay.UTC(), toDay.UTC()).
+		Group("value").
+		Order("clicks DESC, value").
+		Limit(limit).
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("репозиторий: получение разбивки %s: %w", dimension, result.Error)
+	}
+	return rows, nil
+}
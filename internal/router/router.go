@@ -1,46 +1,111 @@
 package router
 
 import (
+	"fmt"
+
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"gorm.io/gorm"
 
 	"tinyurl/internal/config"
 	"tinyurl/internal/handler"
+	"tinyurl/internal/metrics"
 	"tinyurl/internal/middleware"
+	"tinyurl/internal/ratelimit"
 	"tinyurl/internal/repository"
 	"tinyurl/internal/service"
 	"tinyurl/pkg/snowflake"
 )
 
 // New создаёт и настраивает chi-роутер со всеми маршрутами и middleware.
-func New(cfg *config.Config, db *gorm.DB) chi.Router {
+// События переходов передаются в clicks, метрики запросов и сервиса — в m;
+// geo определяет страну посетителя при редиректе, domains (если не nil) проверяет
+// домены новых ссылок.
+func New(
+	cfg *config.Config,
+	storage *repository.Storage,
+	clicks handler.ClickRecorder,
+	urlCache service.URLCache,
+	m *metrics.Metrics,
+	geo handler.CountryResolver,
+	domains service.DomainPolicy,
+) chi.Router {
 	sf, err := snowflake.New(cfg.App.SnowflakeNode)
 	if err != nil {
 		panic("роутер: ошибка инициализации snowflake: " + err.Error())
 	}
 
-	repo := repository.NewURLRepository(db)
-	svc := service.NewURLService(repo, sf, cfg.App.BaseURL)
+	if !service.ValidRedirectType(cfg.App.DefaultRedirectType) {
+		panic(fmt.Sprintf("роутер: недопустимый app.default_redirect_type %d", cfg.App.DefaultRedirectType))
+	}
+
+	svc := service.NewURLService(storage.URLs, sf, cfg.App.BaseURL, cfg.App.DefaultRedirectType, urlCache, m)
+	svc.SetDomainPolicy(domains)
+	statsSvc := service.NewStatsService(storage.URLs, storage.Stats)
+	trusted, err := middleware.ParseTrustedProxies(cfg.RateLimit.TrustedProxies)
+	if err != nil {
+		panic("роутер: " + err.Error())
+	}
+	limits := middleware.NewRateLimits(rateLimitPolicies(cfg.RateLimit.Policies))
+	auth := middleware.Auth(service.NewAPIKeyService(storage.APIKeys), cfg.Auth.Required)
 
 	homeH := handler.NewHomeHandler()
 	shortenH := handler.NewShortenHandler(svc)
-	redirectH := handler.NewRedirectHandler(svc)
+	redirectH := handler.NewRedirectHandler(svc, clicks, geo, cfg.App.PermanentRedirectMaxAge)
+	urlsH := handler.NewURLsHandler(svc)
+	statsH := handler.NewStatsHandler(statsSvc)
 	healthH := handler.NewHealthHandler(svc)
 
 	r := chi.NewRouter()
 	r.Use(chimw.Recoverer)
 	r.Use(chimw.RequestID)
+	r.Use(middleware.RealIP(trusted))
+	r.Use(middleware.Tracing)
 	r.Use(middleware.Logging)
+	r.Use(middleware.Metrics(m))
 
 	r.Get("/", homeH.Home)
 	r.Get("/health", healthH.Health)
 	r.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
 	))
-	r.Post("/api/v1/shorten", shortenH.Shorten)
-	r.Get("/{shortURL}", redirectH.Redirect)
+	r.With(auth, limits.For("shorten")).Post("/api/v1/shorten", shortenH.Shorten)
+	r.Route("/api/v1/urls/{code}", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(limits.For("manage"))
+			r.Get("/qr", urlsH.QR)
+		})
+
+		// Сведения о ссылке и история (раскрывают оригинальные URL, в том числе защищённой
+		// паролем ссылки), статистика переходов и изменяющие маршруты требуют ключ API
+		// (если включено auth.required).
+		// Ограничитель стоит после Auth, чтобы считать запросы по ключу, а не по IP.
+		r.Group(func(r chi.Router) {
+			r.Use(auth, limits.For("manage"))
+			r.Get("/", urlsH.Get)
+			r.Get("/history", urlsH.History)
+			r.Get("/stats", statsH.Stats)
+			r.Patch("/", urlsH.Update)
+			r.Delete("/", urlsH.Delete)
+			r.Post("/rollback", urlsH.Rollback)
+			r.Post("/disable", urlsH.Disable)
+			r.Post("/enable", urlsH.Enable)
+		})
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(limits.For("redirect"))
+		r.Get("/{shortURL}", redirectH.Redirect)
+		r.Post("/{shortURL}", redirectH.Unlock)
+	})
 
 	return r
 }
+
+// rateLimitPolicies преобразует политики из конфигурации.
+func rateLimitPolicies(cfg map[string]config.RateLimitPolicy) map[string]ratelimit.Policy {
+	policies := make(map[string]ratelimit.Policy, len(cfg))
+	for name, p := range cfg {
+		policies[name] = ratelimit.Policy{Requests: p.Requests, Period: p.Period, Burst: p.Burst}
+	}
+	return policies
+}
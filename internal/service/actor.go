@@ -0,0 +1,22 @@
+package service
+
+import "context"
+
+// anonymousActor — инициатор изменений, если он не указан в контексте.
+const anonymousActor = "anonymous"
+
+type actorKey struct{}
+
+// WithActor возвращает контекст с именем инициатора изменений.
+// Имя сохраняется в истории изменений ссылки.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom возвращает инициатора изменений из контекста.
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
@@ -0,0 +1,41 @@
+package service
+
+import "strings"
+
+const (
+	// aliasMinLength — минимальная длина пользовательского кода.
+	aliasMinLength = 3
+	// aliasMaxLength — максимальная длина кода, ограничена колонкой short_url (size:12).
+	aliasMaxLength = 12
+)
+
+// reservedAliases — коды, совпадающие с маршрутами сервиса. metrics обслуживается
+// отдельным сервером, но зарезервирован, чтобы ссылка не перекрывала привычный путь.
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"health":  {},
+	"metrics": {},
+	"swagger": {},
+}
+
+// validateAlias проверяет пользовательский код короткой ссылки:
+// длину, допустимые символы (латиница, цифры, '-' и '_') и зарезервированные имена.
+func validateAlias(alias string) error {
+	if len(alias) < aliasMinLength || len(alias) > aliasMaxLength {
+		return ErrInvalidAlias
+	}
+
+	for _, c := range alias {
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-', c == '_':
+		default:
+			return ErrInvalidAlias
+		}
+	}
+
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return ErrInvalidAlias
+	}
+
+	return nil
+}
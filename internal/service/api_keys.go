@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tinyurl/internal/model"
+	"tinyurl/internal/repository"
+)
+
+// apiKeyPrefix — префикс, по которому ключи сервиса легко опознать в логах и конфигах.
+const apiKeyPrefix = "tu_"
+
+// apiKeyBytes — сколько случайных байт в ключе.
+const apiKeyBytes = 24
+
+var (
+	// ErrInvalidAPIKey — ключ не найден или отозван.
+	ErrInvalidAPIKey = errors.New("недействительный ключ api")
+	// ErrAPIKeyNotFound — ключ с таким ID не найден или уже отозван.
+	ErrAPIKeyNotFound = errors.New("ключ api не найден")
+	// ErrInvalidAPIKeyName — имя ключа пустое или слишком длинное.
+	ErrInvalidAPIKeyName = errors.New("некорректное имя ключа api")
+)
+
+// Caller — владелец ключа, от имени которого выполняется запрос.
+type Caller struct {
+	KeyID int64
+	Name  string
+}
+
+// APIKeyService — сервис выпуска и проверки ключей API.
+type APIKeyService struct {
+	repo repository.APIKeyStore
+}
+
+// NewAPIKeyService создаёт новый экземпляр сервиса ключей.
+func NewAPIKeyService(repo repository.APIKeyStore) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// Create выпускает новый ключ с указанным именем. Открытый ключ возвращается
+// только здесь: в БД сохраняется его SHA-256.
+func (s *APIKeyService) Create(ctx context.Context, name string) (string, *model.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", nil, ErrInvalidAPIKeyName
+	}
+
+	buf := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("сервис: генерация ключа api: %w", err)
+	}
+	plain := apiKeyPrefix + hex.EncodeToString(buf)
+
+	key := &model.APIKey{
+		Name:    name,
+		Prefix:  plain[:len(apiKeyPrefix)+8],
+		KeyHash: hashAPIKey(plain),
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return "", nil, fmt.Errorf("сервис: сохранение ключа api: %w", err)
+	}
+
+	return plain, key, nil
+}
+
+// Authenticate проверяет ключ и возвращает его владельца.
+// Неизвестный или отозванный ключ даёт ErrInvalidAPIKey.
+func (s *APIKeyService) Authenticate(ctx context.Context, plain string) (*Caller, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindActiveByHash(ctx, hashAPIKey(plain))
+	if err != nil {
+		return nil, fmt.Errorf("сервис: проверка ключа api: %w", err)
+	}
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return &Caller{KeyID: key.ID, Name: key.Name}, nil
+}
+
+// List возвращает все выпущенные ключи.
+func (s *APIKeyService) List(ctx context.Context) ([]model.APIKey, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: список ключей api: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke отзывает ключ. Отозванный ключ перестаёт проходить проверку сразу.
+func (s *APIKeyService) Revoke(ctx context.Context, id int64) error {
+	found, err := s.repo.Revoke(ctx, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("сервис: отзыв ключа api: %w", err)
+	}
+	if !found {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// hashAPIKey возвращает SHA-256 ключа в hex. Ключи случайные и длинные,
+// поэтому медленный хеш вроде bcrypt здесь не нужен и лишь замедлил бы каждый запрос.
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
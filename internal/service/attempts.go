@@ -0,0 +1,91 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// unlockMaxAttempts — сколько неверных паролей один клиент может ввести для кода за окно.
+	unlockMaxAttempts = 5
+	// unlockWindow — окно, по истечении которого счётчик неверных попыток сбрасывается.
+	unlockWindow = 15 * time.Minute
+	// attemptsSweepThreshold — размер таблицы, при котором удаляются устаревшие записи.
+	attemptsSweepThreshold = 10000
+)
+
+// attemptLimiter ограничивает число неверных попыток ввода пароля для пары
+// «код, клиент». Счётчик на один код позволял бы любому заблокировать ссылку
+// для владельца пароля, перебирая неверные пароли. Счётчики хранятся в памяти
+// процесса и сбрасываются по истечении окна.
+type attemptLimiter struct {
+	mu      sync.Mutex
+	max     int
+	window  time.Duration
+	entries map[string]*attemptEntry
+}
+
+type attemptEntry struct {
+	failures int
+	resetAt  time.Time
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		max:     max,
+		window:  window,
+		entries: make(map[string]*attemptEntry),
+	}
+}
+
+// Allow сообщает, можно ли сейчас проверить пароль для кода, введённый клиентом client.
+func (l *attemptLimiter) Allow(code, client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[attemptKey(code, client)]
+	if !ok || !now.Before(e.resetAt) {
+		return true
+	}
+	return e.failures < l.max
+}
+
+// Fail засчитывает неверную попытку клиента для кода.
+func (l *attemptLimiter) Fail(code, client string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := attemptKey(code, client)
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.resetAt) {
+		if len(l.entries) >= attemptsSweepThreshold {
+			l.sweep(now)
+		}
+		e = &attemptEntry{resetAt: now.Add(l.window)}
+		l.entries[key] = e
+	}
+	e.failures++
+}
+
+// Reset сбрасывает счётчик клиента после успешного ввода пароля.
+func (l *attemptLimiter) Reset(code, client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, attemptKey(code, client))
+}
+
+// attemptKey — ключ счётчика попыток. Код не содержит нулевого байта, поэтому
+// разные пары не дают одинаковых ключей.
+func attemptKey(code, client string) string {
+	return code + "\x00" + client
+}
+
+// sweep удаляет записи с истёкшим окном. Вызывается под мьютексом.
+func (l *attemptLimiter) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if !now.Before(e.resetAt) {
+			delete(l.entries, key)
+		}
+	}
+}
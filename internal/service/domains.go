@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+
+	"tinyurl/internal/blocklist"
+	"tinyurl/internal/model"
+)
+
+// DomainPolicy решает, можно ли сокращать ссылки на хост (см. blocklist.List).
+type DomainPolicy interface {
+	Blocked(host string) bool
+}
+
+// disableBatchSize — сколько ссылок просматривать за один запрос в DisableDomain.
+const disableBatchSize = 500
+
+// SetDomainPolicy включает проверку доменов: Shorten и UpdateDestination отклоняют
+// ссылки на заблокированные хосты с ErrBlockedDomain. По умолчанию проверки нет.
+func (s *URLService) SetDomainPolicy(policy DomainPolicy) {
+	s.domains = policy
+}
+
+// checkDomains проверяет хосты всех адресов ссылки: оригинального URL и адресов
+// из правил выбора URL, чтобы заблокированный домен нельзя было спрятать в варианте.
+func (s *URLService) checkDomains(url *model.URL) error {
+	if s.domains == nil {
+		return nil
+	}
+	for _, target := range destinations(url) {
+		if host := hostOf(target); s.domains.Blocked(host) {
+			return fmt.Errorf("%w: %s", ErrBlockedDomain, host)
+		}
+	}
+	return nil
+}
+
+// DisableDomain отключает все включённые ссылки, у которых оригинальный URL или
+// один из адресов правил ведёт на домен или его поддомен, и возвращает их коды.
+func (s *URLService) DisableDomain(ctx context.Context, domain string) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "URLService.DisableDomain", domain)
+	defer func() { endSpan(span, err) }()
+
+	if blocklist.Normalize(domain) == "" {
+		return nil, ErrInvalidDomain
+	}
+
+	var disabled []string
+	for afterID := int64(0); ; {
+		urls, err := s.repo.FindEnabled(ctx, afterID, disableBatchSize)
+		if err != nil {
+			return disabled, fmt.Errorf("сервис: поиск ссылок: %w", err)
+		}
+		for i := range urls {
+			if !pointsTo(&urls[i], domain) {
+				continue
+			}
+			if _, err := s.repo.SetDisabled(ctx, urls[i].ShortURL, true); err != nil {
+				return disabled, fmt.Errorf("сервис: отключение url: %w", err)
+			}
+			s.invalidate(ctx, urls[i].ShortURL)
+			disabled = append(disabled, urls[i].ShortURL)
+		}
+		if len(urls) < disableBatchSize {
+			return disabled, nil
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+}
+
+// pointsTo сообщает, ведёт ли какой-либо адрес ссылки на домен или его поддомен.
+func pointsTo(url *model.URL, domain string) bool {
+	for _, target := range destinations(url) {
+		if blocklist.InDomain(hostOf(target), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// destinations возвращает все адреса, на которые может вести ссылка.
+func destinations(url *model.URL) []string {
+	out := []string{url.LongURL}
+	if t := url.Targeting; t != nil {
+		for _, u := range t.Devices {
+			out = append(out, u)
+		}
+		for _, u := range t.Countries {
+			out = append(out, u)
+		}
+		for _, w := range t.Schedule {
+			out = append(out, w.URL)
+		}
+		for _, v := range t.Variants {
+			out = append(out, v.URL)
+		}
+	}
+	return out
+}
+
+// hostOf возвращает хост URL без порта и учётных данных
+// (для https://bank.example@evil.example/ — evil.example).
+func hostOf(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tinyurl/internal/model"
+)
+
+// URLInfo — полные сведения о короткой ссылке для API управления.
+type URLInfo struct {
+	Code              string
+	ShortURL          string
+	LongURL           string
+	CreatedAt         time.Time
+	ExpiresAt         *time.Time
+	NotBefore         *time.Time
+	NotAfter          *time.Time
+	MaxClicks         *int64
+	ClickCount        int64
+	PasswordProtected bool
+	Disabled          bool
+	RedirectType      int
+	DeviceTargets     map[string]string
+	CountryTargets    map[string]string
+	Schedule          []ScheduleWindow
+	Variants          []Variant
+}
+
+// Get возвращает сведения о ссылке по коду.
+func (s *URLService) Get(ctx context.Context, shortCode string) (_ *URLInfo, err error) {
+	ctx, span := startSpan(ctx, "URLService.Get", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	url, err := s.repo.FindByShortURL(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: получение url: %w", err)
+	}
+	if url == nil {
+		return nil, ErrNotFound
+	}
+	return s.info(url), nil
+}
+
+// HistoryEntry — одно изменение оригинального URL ссылки.
+type HistoryEntry struct {
+	Version    int
+	OldLongURL string
+	NewLongURL string
+	ChangedBy  string
+	ChangedAt  time.Time
+}
+
+// UpdateDestination меняет оригинальный URL, на который ведёт ссылка.
+// Изменение записывается в историю от имени инициатора из контекста (см. WithActor).
+// URL на заблокированный домен отклоняется с ErrBlockedDomain.
+func (s *URLService) UpdateDestination(ctx context.Context, shortCode, longURL string) (_ *URLInfo, err error) {
+	ctx, span := startSpan(ctx, "URLService.UpdateDestination", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	if err := s.checkDomains(&model.URL{LongURL: longURL}); err != nil {
+		return nil, err
+	}
+
+	found, err := s.repo.UpdateLongURL(ctx, shortCode, longURL, actorFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("сервис: изменение url: %w", err)
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	s.invalidate(ctx, shortCode)
+	return s.Get(ctx, shortCode)
+}
+
+// History возвращает изменения оригинального URL ссылки по возрастанию версии.
+func (s *URLService) History(ctx context.Context, shortCode string) (_ []HistoryEntry, err error) {
+	ctx, span := startSpan(ctx, "URLService.History", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	url, err := s.repo.FindByShortURL(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: получение url: %w", err)
+	}
+	if url == nil {
+		return nil, ErrNotFound
+	}
+
+	history, err := s.repo.FindHistory(ctx, url.ID)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: получение истории: %w", err)
+	}
+
+	entries := make([]HistoryEntry, 0, len(history))
+	for _, h := range history {
+		entries = append(entries, HistoryEntry{
+			Version:    h.Version,
+			OldLongURL: h.OldLongURL,
+			NewLongURL: h.NewLongURL,
+			ChangedBy:  h.ChangedBy,
+			ChangedAt:  h.ChangedAt,
+		})
+	}
+	return entries, nil
+}
+
+// Rollback отменяет изменение с указанной версией: возвращает ссылке оригинальный URL,
+// действовавший до этого изменения. Откат сам записывается в историю как новая версия.
+func (s *URLService) Rollback(ctx context.Context, shortCode string, version int) (_ *URLInfo, err error) {
+	ctx, span := startSpan(ctx, "URLService.Rollback", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	url, err := s.repo.FindByShortURL(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: получение url: %w", err)
+	}
+	if url == nil {
+		return nil, ErrNotFound
+	}
+
+	entry, err := s.repo.FindHistoryVersion(ctx, url.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: получение версии: %w", err)
+	}
+	if entry == nil {
+		return nil, ErrVersionNotFound
+	}
+
+	return s.UpdateDestination(ctx, shortCode, entry.OldLongURL)
+}
+
+// SetDisabled отключает ссылку или включает её обратно.
+// Отключённая ссылка хранится, но редирект по ней возвращает ErrDisabled.
+func (s *URLService) SetDisabled(ctx context.Context, shortCode string, disabled bool) (_ *URLInfo, err error) {
+	ctx, span := startSpan(ctx, "URLService.SetDisabled", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	found, err := s.repo.SetDisabled(ctx, shortCode, disabled)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: изменение статуса url: %w", err)
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	s.invalidate(ctx, shortCode)
+	return s.Get(ctx, shortCode)
+}
+
+// Delete удаляет ссылку.
+func (s *URLService) Delete(ctx context.Context, shortCode string) (err error) {
+	ctx, span := startSpan(ctx, "URLService.Delete", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	found, err := s.repo.DeleteByShortURL(ctx, shortCode)
+	if err != nil {
+		return fmt.Errorf("сервис: удаление url: %w", err)
+	}
+	if !found {
+		return ErrNotFound
+	}
+	s.invalidate(ctx, shortCode)
+	return nil
+}
+
+// info формирует сведения о ссылке по сохранённой записи.
+func (s *URLService) info(url *model.URL) *URLInfo {
+	info := &URLInfo{
+		Code:              url.ShortURL,
+		ShortURL:          s.baseURL + "/" + url.ShortURL,
+		LongURL:           url.LongURL,
+		CreatedAt:         url.CreatedAt,
+		ExpiresAt:         url.ExpiresAt,
+		NotBefore:         url.NotBefore,
+		NotAfter:          url.NotAfter,
+		MaxClicks:         url.MaxClicks,
+		ClickCount:        url.ClickCount,
+		PasswordProtected: url.PasswordHash != "",
+		Disabled:          url.Disabled,
+		RedirectType:      url.RedirectType,
+	}
+	if url.Targeting != nil {
+		info.DeviceTargets = url.Targeting.Devices
+		info.CountryTargets = url.Targeting.Countries
+		info.Schedule = url.Targeting.Schedule
+		info.Variants = url.Targeting.Variants
+	}
+	return info
+}
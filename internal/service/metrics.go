@@ -0,0 +1,21 @@
+package service
+
+// Metrics — получатель событий сервиса ссылок для метрик (реализуется internal/metrics).
+type Metrics interface {
+	// Shortened — ссылка сокращена; deduplicated — возвращена уже существующая.
+	Shortened(deduplicated bool)
+	// Redirected — ссылка разрешена для перехода.
+	Redirected()
+	// NotFound — запрошен неизвестный код.
+	NotFound()
+	// IDGenerated — выдан новый Snowflake ID.
+	IDGenerated()
+}
+
+// NopMetrics — Metrics, который ничего не считает.
+type NopMetrics struct{}
+
+func (NopMetrics) Shortened(bool) {}
+func (NopMetrics) Redirected()    {}
+func (NopMetrics) NotFound()      {}
+func (NopMetrics) IDGenerated()   {}
@@ -0,0 +1,31 @@
+package service
+
+import "golang.org/x/crypto/bcrypt"
+
+const (
+	// passwordMinLength — минимальная длина пароля ссылки.
+	passwordMinLength = 4
+	// passwordMaxLength — ограничение bcrypt: учитываются только первые 72 байта.
+	passwordMaxLength = 72
+)
+
+// hashPassword возвращает bcrypt-хеш пароля или пустую строку, если пароль не задан.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) < passwordMinLength || len(password) > passwordMaxLength {
+		return "", ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword сравнивает пароль с сохранённым хешем за постоянное время.
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
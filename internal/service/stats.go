@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tinyurl/internal/model"
+	"tinyurl/internal/repository"
+)
+
+// Гранулярность временного ряда статистики.
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+const (
+	// defaultStatsRange — интервал статистики по умолчанию.
+	defaultStatsRange = 7 * 24 * time.Hour
+	// defaultStatsTop — сколько значений возвращать в разбивках по умолчанию.
+	defaultStatsTop = 10
+	// maxStatsTop — максимальное число значений в разбивке.
+	maxStatsTop = 100
+	// maxStatsPoints — максимальное число точек временного ряда.
+	maxStatsPoints = 1000
+)
+
+// StatsService — сервис статистики переходов по ссылкам.
+// Читает только агрегаты, которые строит фоновая агрегация (analytics.Aggregator).
+type StatsService struct {
+	urls  repository.URLStore
+	stats repository.StatsStore
+}
+
+// NewStatsService создаёт новый экземпляр сервиса статистики.
+func NewStatsService(urls repository.URLStore, stats repository.StatsStore) *StatsService {
+	return &StatsService{urls: urls, stats: stats}
+}
+
+// StatsQuery — параметры запроса статистики. Нулевые значения заменяются значениями по умолчанию:
+// последние 7 дней, подневная гранулярность, 10 значений в разбивках.
+type StatsQuery struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Top         int
+}
+
+// StatsPoint — число переходов за час или день, начинающийся в Time.
+type StatsPoint struct {
+	Time   time.Time
+	Clicks int64
+}
+
+// StatsBucket — число переходов с данным значением измерения.
+type StatsBucket struct {
+	Value  string
+	Clicks int64
+}
+
+// Stats — статистика переходов по ссылке.
+type Stats struct {
+	Code        string
+	TotalClicks int64
+	RangeClicks int64
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Series      []StatsPoint
+	Referrers   []StatsBucket
+	Browsers    []StatsBucket
+	OSes        []StatsBucket
+	Devices     []StatsBucket
+	// Countries — переходы по странам (если подключена база GeoIP).
+	Countries []StatsBucket
+	// Variants — переходы по вариантам A/B-теста.
+	Variants []StatsBucket
+}
+
+// Stats возвращает статистику переходов по ссылке за интервал [From, To).
+// Временной ряд дополняется нулями для часов (дней) без переходов.
+// Разбивки считаются по целым дням (UTC), в которые попадает интервал.
+func (s *StatsService) Stats(ctx context.Context, shortCode string, q StatsQuery) (*Stats, error) {
+	q, err := normalizeStatsQuery(q, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.urls.FindByShortURL(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: получение url: %w", err)
+	}
+	if url == nil {
+		return nil, ErrNotFound
+	}
+
+	hourly, err := s.stats.FindHourly(ctx, url.ID, q.From, q.To)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: получение статистики: %w", err)
+	}
+
+	stats := &Stats{
+		Code:        url.ShortURL,
+		TotalClicks: url.ClickCount,
+		From:        q.From,
+		To:          q.To,
+		Granularity: q.Granularity,
+		Series:      buildSeries(hourly, q),
+	}
+	for _, p := range stats.Series {
+		stats.RangeClicks += p.Clicks
+	}
+
+	fromDay := truncateDay(q.From)
+	toDay := truncateDay(q.To.Add(-time.Nanosecond))
+	for dimension, dst := range map[string]*[]StatsBucket{
+		model.DimensionReferrer: &stats.Referrers,
+		model.DimensionBrowser:  &stats.Browsers,
+		model.DimensionOS:       &stats.OSes,
+		model.DimensionDevice:   &stats.Devices,
+		model.DimensionCountry:  &stats.Countries,
+		model.DimensionVariant:  &stats.Variants,
+	} {
+		rows, err := s.stats.TopDimension(ctx, url.ID, dimension, fromDay, toDay, q.Top)
+		if err != nil {
+			return nil, fmt.Errorf("сервис: получение статистики: %w", err)
+		}
+		buckets := make([]StatsBucket, 0, len(rows))
+		for _, row := range rows {
+			buckets = append(buckets, StatsBucket{Value: row.Value, Clicks: row.Clicks})
+		}
+		*dst = buckets
+	}
+
+	return stats, nil
+}
+
+// normalizeStatsQuery подставляет значения по умолчанию, выравнивает границы интервала
+// по шагу ряда и проверяет ограничения. Некорректный запрос даёт ErrInvalidStatsQuery.
+func normalizeStatsQuery(q StatsQuery, now time.Time) (StatsQuery, error) {
+	if q.Granularity == "" {
+		q.Granularity = GranularityDay
+	}
+	step, ok := granularityStep(q.Granularity)
+	if !ok {
+		return q, ErrInvalidStatsQuery
+	}
+
+	if q.To.IsZero() {
+		q.To = now
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-defaultStatsRange)
+	}
+
+	// Начало округляется вниз, конец — вверх до границы шага
+	to := truncateStep(q.To.UTC(), step)
+	if to.Before(q.To) {
+		to = to.Add(step)
+	}
+	q.From = truncateStep(q.From.UTC(), step)
+	q.To = to
+	if !q.From.Before(q.To) || q.To.Sub(q.From)/step > maxStatsPoints {
+		return q, ErrInvalidStatsQuery
+	}
+
+	if q.Top == 0 {
+		q.Top = defaultStatsTop
+	}
+	if q.Top < 0 || q.Top > maxStatsTop {
+		return q, ErrInvalidStatsQuery
+	}
+
+	return q, nil
+}
+
+// buildSeries сворачивает почасовые агрегаты в ряд с шагом q.Granularity,
+// заполняя пропуски нулями.
+func buildSeries(hourly []model.ClickHourly, q StatsQuery) []StatsPoint {
+	step, _ := granularityStep(q.Granularity)
+
+	byTime := make(map[time.Time]int64, len(hourly))
+	for _, h := range hourly {
+		byTime[truncateStep(h.Hour.UTC(), step)] += h.Clicks
+	}
+
+	series := make([]StatsPoint, 0, q.To.Sub(q.From)/step)
+	for t := q.From; t.Before(q.To); t = t.Add(step) {
+		series = append(series, StatsPoint{Time: t, Clicks: byTime[t]})
+	}
+	return series
+}
+
+func granularityStep(granularity string) (time.Duration, bool) {
+	switch granularity {
+	case GranularityHour:
+		return time.Hour, true
+	case GranularityDay:
+		return 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// truncateStep округляет момент UTC вниз до начала часа или дня.
+func truncateStep(t time.Time, step time.Duration) time.Time {
+	if step == 24*time.Hour {
+		return truncateDay(t)
+	}
+	return t.Truncate(step)
+}
+
+// truncateDay возвращает начало дня (UTC), в который попадает t.
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"hash/fnv"
+	"net/url"
+	"strings"
+	"time"
+
+	"tinyurl/internal/model"
+	"tinyurl/pkg/useragent"
+)
+
+// Visitor — сведения о посетителе короткой ссылки, по которым выбирается
+// оригинальный URL (см. model.Targeting).
+type Visitor struct {
+	UserAgent string
+	// ID — постоянный идентификатор посетителя (например, из cookie): по нему
+	// посетитель закрепляется за вариантом A/B-теста.
+	ID string
+	// Country — страна посетителя (ISO 3166-1 alpha-2); пустая — неизвестна.
+	Country string
+	// IP — адрес клиента: по нему считаются неверные попытки ввода пароля.
+	IP string
+}
+
+// Variant — вариант A/B-теста ссылки: имя, оригинальный URL и вес.
+type Variant = model.Variant
+
+// ScheduleWindow — оригинальный URL, действующий в интервале [From, To).
+type ScheduleWindow = model.ScheduleWindow
+
+// Ограничения A/B-теста и расписания ссылки.
+const (
+	minVariants        = 2
+	maxVariants        = 10
+	maxVariantNameLen  = 64
+	maxVariantWeight   = 1000
+	maxScheduleWindows = 20
+)
+
+// platform определяет платформу посетителя по User-Agent: ios, android, desktop
+// или пустую строку, если платформу распознать не удалось (в том числе для ботов).
+func (v Visitor) platform() string {
+	info := useragent.Parse(v.UserAgent)
+	switch {
+	case info.OS == useragent.OSIOS:
+		return model.PlatformIOS
+	case info.OS == useragent.OSAndroid:
+		return model.PlatformAndroid
+	case info.Device == useragent.DeviceDesktop:
+		return model.PlatformDesktop
+	}
+	return ""
+}
+
+// destination выбирает оригинальный URL для посетителя в момент now: сначала
+// по платформе, затем по стране, затем по расписанию, затем по варианту A/B-теста,
+// а если ни одно правило не подошло — URL.LongURL. variant — имя выбранного
+// варианта или пустая строка.
+func destination(u *model.URL, v Visitor, now time.Time) (longURL, variant string) {
+	if u.Targeting.IsEmpty() {
+		return u.LongURL, ""
+	}
+	if longURL, ok := u.Targeting.Devices[v.platform()]; ok {
+		return longURL, ""
+	}
+	if longURL, ok := u.Targeting.Countries[v.Country]; ok && v.Country != "" {
+		return longURL, ""
+	}
+	for _, w := range u.Targeting.Schedule {
+		if w.Contains(now) {
+			return w.URL, ""
+		}
+	}
+	if len(u.Targeting.Variants) > 0 {
+		chosen := pickVariant(u.Targeting.Variants, u.ShortURL, v.ID)
+		return chosen.URL, chosen.Name
+	}
+	return u.LongURL, ""
+}
+
+// validUntil возвращает ближайший после now момент, когда ответ по ссылке может
+// измениться: истечение срока действия, конец окна активности или граница окна
+// расписания. nil — ответ не меняется со временем.
+func validUntil(u *model.URL, now time.Time) *time.Time {
+	var earliest *time.Time
+	consider := func(t *time.Time) {
+		if t != nil && t.After(now) && (earliest == nil || t.Before(*earliest)) {
+			earliest = t
+		}
+	}
+
+	consider(u.ExpiresAt)
+	consider(u.NotAfter)
+	if u.Targeting != nil {
+		for _, w := range u.Targeting.Schedule {
+			consider(w.From)
+			consider(w.To)
+		}
+	}
+	return earliest
+}
+
+// pickVariant выбирает вариант пропорционально весам по хешу кода ссылки
+// и идентификатора посетителя: один и тот же посетитель всегда попадает
+// в один вариант, пока набор вариантов не меняется.
+func pickVariant(variants []model.Variant, shortCode, visitorID string) model.Variant {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(shortCode))
+	h.Write([]byte{0})
+	h.Write([]byte(visitorID))
+	n := int(h.Sum64() % uint64(total))
+
+	for _, v := range variants {
+		if n < v.Weight {
+			return v
+		}
+		n -= v.Weight
+	}
+	return variants[len(variants)-1]
+}
+
+// newTargeting проверяет правила выбора URL из параметров сокращения.
+// Возвращает nil, если правил нет, и ErrInvalidTargeting для неизвестной
+// платформы или страны, URL не по http(s) или некорректного набора вариантов,
+// ErrInvalidSchedule — для некорректного расписания. Коды стран приводятся
+// к верхнему регистру, границы окон расписания — к UTC.
+func newTargeting(params ShortenParams) (*model.Targeting, error) {
+	devices, variants := params.DeviceTargets, params.Variants
+	if len(devices) == 0 && len(params.CountryTargets) == 0 && len(params.Schedule) == 0 && len(variants) == 0 {
+		return nil, nil
+	}
+	if err := validateVariants(variants); err != nil {
+		return nil, err
+	}
+	schedule, err := normalizeSchedule(params.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	countries, err := normalizeCountries(params.CountryTargets)
+	if err != nil {
+		return nil, err
+	}
+	for platform, longURL := range devices {
+		switch platform {
+		case model.PlatformIOS, model.PlatformAndroid, model.PlatformDesktop:
+		default:
+			return nil, ErrInvalidTargeting
+		}
+		if !validTargetURL(longURL) {
+			return nil, ErrInvalidTargeting
+		}
+	}
+	t := &model.Targeting{Countries: countries, Schedule: schedule, Variants: variants}
+	if len(devices) > 0 {
+		t.Devices = devices
+	}
+	return t, nil
+}
+
+// normalizeCountries проверяет правила по странам и приводит коды к верхнему регистру.
+func normalizeCountries(targets map[string]string) (map[string]string, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	countries := make(map[string]string, len(targets))
+	for code, longURL := range targets {
+		code = strings.ToUpper(code)
+		if !validCountryCode(code) || !validTargetURL(longURL) {
+			return nil, ErrInvalidTargeting
+		}
+		if _, dup := countries[code]; dup {
+			return nil, ErrInvalidTargeting
+		}
+		countries[code] = longURL
+	}
+	return countries, nil
+}
+
+// normalizeSchedule проверяет окна расписания: их не больше maxScheduleWindows,
+// у каждого есть хотя бы одна граница, From раньше To, URL по http(s).
+func normalizeSchedule(windows []ScheduleWindow) ([]ScheduleWindow, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+	if len(windows) > maxScheduleWindows {
+		return nil, ErrInvalidSchedule
+	}
+	out := make([]ScheduleWindow, 0, len(windows))
+	for _, w := range windows {
+		if w.From == nil && w.To == nil || !validTargetURL(w.URL) {
+			return nil, ErrInvalidSchedule
+		}
+		if w.From != nil && w.To != nil && !w.From.Before(*w.To) {
+			return nil, ErrInvalidSchedule
+		}
+		out = append(out, ScheduleWindow{From: utcTime(w.From), To: utcTime(w.To), URL: w.URL})
+	}
+	return out, nil
+}
+
+// utcTime возвращает копию момента в UTC или nil.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// validCountryCode сообщает, что код похож на ISO 3166-1 alpha-2: две латинские буквы.
+func validCountryCode(code string) bool {
+	return len(code) == 2 &&
+		code[0] >= 'A' && code[0] <= 'Z' &&
+		code[1] >= 'A' && code[1] <= 'Z'
+}
+
+// validateVariants проверяет варианты A/B-теста: их от minVariants до maxVariants,
+// имена непустые и различаются, веса от 1 до maxVariantWeight, URL по http(s).
+// Пустой список допустим — теста нет.
+func validateVariants(variants []model.Variant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	if len(variants) < minVariants || len(variants) > maxVariants {
+		return ErrInvalidTargeting
+	}
+	names := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if v.Name == "" || len(v.Name) > maxVariantNameLen || names[v.Name] {
+			return ErrInvalidTargeting
+		}
+		if v.Weight < 1 || v.Weight > maxVariantWeight || !validTargetURL(v.URL) {
+			return ErrInvalidTargeting
+		}
+		names[v.Name] = true
+	}
+	return nil
+}
+
+// validTargetURL сообщает, что URL абсолютный и ведёт по http или https.
+func validTargetURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
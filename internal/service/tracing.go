@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("tinyurl/internal/service")
+
+// startSpan открывает спан метода сервиса; code — код ссылки, если он известен.
+func startSpan(ctx context.Context, name, code string) (context.Context, trace.Span) {
+	var opts []trace.SpanStartOption
+	if code != "" {
+		opts = append(opts, trace.WithAttributes(attribute.String("tinyurl.code", code)))
+	}
+	return tracer.Start(ctx, name, opts...)
+}
+
+// domainErrors — ожидаемые ошибки сервиса: неизвестный код, истёкшая ссылка,
+// неверный ввод и т. п. Это обычный исход запроса, а не сбой.
+var domainErrors = []error{
+	ErrNotFound, ErrVersionNotFound, ErrInvalidStatsQuery,
+	ErrInvalidAlias, ErrAliasTaken, ErrInvalidExpiry,
+	ErrDisabled, ErrExpired, ErrNotYetActive,
+	ErrInvalidMaxClicks, ErrClickLimitReached, ErrInvalidRedirectType,
+	ErrInvalidSchedule, ErrBlockedDomain, ErrInvalidDomain, ErrInvalidTargeting,
+	ErrInvalidPassword, ErrPasswordRequired, ErrWrongPassword, ErrTooManyAttempts,
+}
+
+// endSpan записывает ошибку метода (если есть) и закрывает спан.
+// Статус Error ставится только при сбоях инфраструктуры, чтобы ожидаемые
+// ответы вроде 404 и 410 не выглядели в трассировках как отказы.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !isDomainError(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+func isDomainError(err error) bool {
+	for _, target := range domainErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,77 +2,490 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
+	"tinyurl/internal/cache"
 	"tinyurl/internal/model"
 	"tinyurl/internal/repository"
 	"tinyurl/pkg/base62"
 	"tinyurl/pkg/snowflake"
 )
 
+// URLCache — кэш записей ссылок по коду перед БД.
+// Get с ok = true и url = nil означает закэшированное отсутствие кода.
+type URLCache interface {
+	Get(ctx context.Context, code string) (url *model.URL, ok bool)
+	Set(ctx context.Context, code string, url *model.URL)
+	Delete(ctx context.Context, code string)
+	// Version и Fill кэшируют прочитанное из БД, только если код не
+	// инвалидировали, пока шло чтение.
+	Version(ctx context.Context, code string) cache.Version
+	Fill(ctx context.Context, code string, url *model.URL, v cache.Version)
+	Stats() cache.Stats
+}
+
 // URLService — сервис сокращения ссылок.
 type URLService struct {
-	repo    *repository.URLRepository
-	sf      *snowflake.Generator
-	baseURL string
+	repo     repository.URLStore
+	sf       *snowflake.Generator
+	baseURL  string
+	attempts *attemptLimiter
+	cache    URLCache
+	metrics  Metrics
+	// defaultRedirect — тип редиректа для ссылок, созданных без redirect_type.
+	defaultRedirect int
+	// now — источник текущего времени для окон активности и расписаний.
+	now func() time.Time
+	// domains — проверка доменов ссылок; nil — без проверки.
+	domains DomainPolicy
 }
 
-// NewURLService создаёт новый экземпляр сервиса.
-func NewURLService(repo *repository.URLRepository, sf *snowflake.Generator, baseURL string) *URLService {
+// NewURLService создаёт новый экземпляр сервиса. defaultRedirect — тип редиректа
+// по умолчанию (см. ValidRedirectType). Если кэш или метрики не нужны,
+// передаются cache.Nop{} и NopMetrics{}.
+func NewURLService(
+	repo repository.URLStore,
+	sf *snowflake.Generator,
+	baseURL string,
+	defaultRedirect int,
+	urlCache URLCache,
+	metrics Metrics,
+) *URLService {
 	return &URLService{
-		repo:    repo,
-		sf:      sf,
-		baseURL: baseURL,
+		repo:            repo,
+		sf:              sf,
+		baseURL:         baseURL,
+		attempts:        newAttemptLimiter(unlockMaxAttempts, unlockWindow),
+		cache:           urlCache,
+		metrics:         metrics,
+		defaultRedirect: defaultRedirect,
+		now:             time.Now,
+	}
+}
+
+// SetClock подменяет источник текущего времени (по умолчанию time.Now): от него
+// зависят проверка окна активности, выбор URL по расписанию и срок действия ссылок.
+func (s *URLService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// ValidRedirectType сообщает, допустим ли HTTP-статус как тип редиректа ссылки:
+// 301 и 308 — постоянные, 302 и 307 — временные; 307 и 308 сохраняют метод запроса.
+func ValidRedirectType(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
 	}
+	return false
+}
+
+// ShortenParams — параметры сокращения ссылки.
+type ShortenParams struct {
+	LongURL string
+	// Alias — пользовательский код; если пуст, код генерируется из Snowflake ID.
+	Alias string
+	// ExpiresAt — момент, после которого ссылка перестаёт работать.
+	ExpiresAt *time.Time
+	// TTL — время жизни ссылки от момента создания (альтернатива ExpiresAt).
+	TTL time.Duration
+	// NotBefore и NotAfter — окно активности: ссылка работает с NotBefore до NotAfter.
+	NotBefore *time.Time
+	NotAfter  *time.Time
+	// MaxClicks — сколько раз можно перейти по ссылке; nil — без ограничений.
+	MaxClicks *int64
+	// Password — пароль для перехода по ссылке; хранится только его bcrypt-хеш.
+	Password string
+	// RedirectType — HTTP-статус редиректа; 0 — значение по умолчанию сервиса.
+	RedirectType int
+	// DeviceTargets — оригинальный URL по платформе посетителя (ios, android, desktop);
+	// посетители остальных платформ переходят на LongURL.
+	DeviceTargets map[string]string
+	// CountryTargets — оригинальный URL по стране посетителя (ISO 3166-1 alpha-2);
+	// посетители из остальных стран переходят на LongURL.
+	CountryTargets map[string]string
+	// Schedule — оригинальный URL по времени перехода (окна [From, To)).
+	Schedule []ScheduleWindow
+	// Variants — варианты A/B-теста с весами: посетитель закрепляется за одним из них
+	// (см. Visitor.ID), выбранный вариант записывается в событие перехода.
+	Variants []Variant
 }
 
 // ShortenResult — результат сокращения ссылки.
 type ShortenResult struct {
-	ShortURL string `json:"short_url"`
+	ShortURL       string            `json:"short_url"`
+	ExpiresAt      *time.Time        `json:"expires_at,omitempty"`
+	NotBefore      *time.Time        `json:"not_before,omitempty"`
+	NotAfter       *time.Time        `json:"not_after,omitempty"`
+	MaxClicks      *int64            `json:"max_clicks,omitempty"`
+	RedirectType   int               `json:"redirect_type"`
+	DeviceTargets  map[string]string `json:"device_targets,omitempty"`
+	CountryTargets map[string]string `json:"country_targets,omitempty"`
+	Schedule       []ScheduleWindow  `json:"schedule,omitempty"`
+	Variants       []Variant         `json:"variants,omitempty"`
 }
 
 // Shorten сокращает длинный URL. Если URL уже был сокращён — возвращает существующий.
-func (s *URLService) Shorten(ctx context.Context, longURL string) (*ShortenResult, error) {
-	// Дедупликация: проверяем, существует ли уже такой URL
-	existing, err := s.repo.FindByLongURL(ctx, longURL)
+// Если задан Alias, ссылка создаётся с этим кодом; занятый код даёт ErrAliasTaken.
+// Ссылка на заблокированный домен (см. SetDomainPolicy) отклоняется с ErrBlockedDomain.
+func (s *URLService) Shorten(ctx context.Context, params ShortenParams) (_ *ShortenResult, err error) {
+	ctx, span := startSpan(ctx, "URLService.Shorten", params.Alias)
+	defer func() { endSpan(span, err) }()
+
+	if params.RedirectType == 0 {
+		params.RedirectType = s.defaultRedirect
+	}
+	url, err := newURL(params, s.now())
 	if err != nil {
-		return nil, fmt.Errorf("сервис: проверка существующего url: %w", err)
+		return nil, err
 	}
-	if existing != nil {
-		return &ShortenResult{
-			ShortURL: s.baseURL + "/" + existing.ShortURL,
-		}, nil
+	if err := s.checkDomains(url); err != nil {
+		return nil, err
 	}
 
-	// Генерация нового короткого URL
-	id := s.sf.Generate()
-	shortCode := base62.Encode(id)
+	if params.Alias != "" {
+		return s.shortenWithAlias(ctx, url, params.Alias)
+	}
 
-	url := &model.URL{
-		ID:       id,
-		ShortURL: shortCode,
-		LongURL:  longURL,
+	// Дедупликация: проверяем, существует ли уже такой URL.
+	// Ссылки со сроком действия, лимитом переходов или правилами выбора URL
+	// всегда создаются заново.
+	if url.IsPlain() {
+		existing, err := s.repo.FindByLongURL(ctx, url.LongURL, url.RedirectType)
+		if err != nil {
+			return nil, fmt.Errorf("сервис: проверка существующего url: %w", err)
+		}
+		if existing != nil {
+			s.metrics.Shortened(true)
+			return s.result(existing), nil
+		}
+	}
+
+	// Генерация нового короткого URL. Сгенерированный код может совпасть с ранее
+	// занятым пользовательским кодом той же длины — тогда берём следующий ID
+	for attempt := 1; ; attempt++ {
+		url.ID = s.nextID()
+		url.ShortURL = base62.Encode(url.ID)
+
+		err := s.repo.Create(ctx, url)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, repository.ErrDuplicate) || attempt == generateAttempts {
+			return nil, fmt.Errorf("сервис: создание url: %w", err)
+		}
+	}
+	s.cache.Set(ctx, url.ShortURL, url)
+	s.metrics.Shortened(false)
+
+	return s.result(url), nil
+}
+
+// shortenWithAlias сохраняет ссылку с пользовательским кодом.
+// Повторный запрос с тем же кодом и тем же URL возвращает существующую ссылку.
+func (s *URLService) shortenWithAlias(ctx context.Context, url *model.URL, alias string) (*ShortenResult, error) {
+	if err := validateAlias(alias); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindByShortURL(ctx, alias)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: проверка кода: %w", err)
+	}
+	if existing != nil {
+		if existing.LongURL == url.LongURL && existing.RedirectType == url.RedirectType &&
+			existing.IsPlain() && url.IsPlain() {
+			s.metrics.Shortened(true)
+			return s.result(existing), nil
+		}
+		return nil, ErrAliasTaken
 	}
 
+	url.ID = s.nextID()
+	url.ShortURL = alias
+
 	if err := s.repo.Create(ctx, url); err != nil {
+		// Код могли занять параллельным запросом между проверкой и вставкой
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAliasTaken
+		}
 		return nil, fmt.Errorf("сервис: создание url: %w", err)
 	}
+	// Запись заменяет и закэшированное отсутствие кода, если его запрашивали раньше
+	s.cache.Set(ctx, url.ShortURL, url)
+	s.metrics.Shortened(false)
+
+	return s.result(url), nil
+}
+
+// generateAttempts — сколько раз Shorten генерирует код, если предыдущий оказался занят.
+const generateAttempts = 3
 
-	return &ShortenResult{
-		ShortURL: s.baseURL + "/" + shortCode,
+// nextID выдаёт новый Snowflake ID.
+func (s *URLService) nextID() int64 {
+	s.metrics.IDGenerated()
+	return s.sf.Generate()
+}
+
+// newURL проверяет параметры сокращения и формирует запись без кода и ID.
+func newURL(params ShortenParams, now time.Time) (*model.URL, error) {
+	expiresAt, err := expiryFromParams(params, now)
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore, notAfter, err := activationWindow(params, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.MaxClicks != nil && *params.MaxClicks <= 0 {
+		return nil, ErrInvalidMaxClicks
+	}
+
+	if !ValidRedirectType(params.RedirectType) {
+		return nil, ErrInvalidRedirectType
+	}
+
+	targeting, err := newTargeting(params)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := hashPassword(params.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.URL{
+		LongURL:      params.LongURL,
+		ExpiresAt:    expiresAt,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		MaxClicks:    params.MaxClicks,
+		PasswordHash: passwordHash,
+		RedirectType: params.RedirectType,
+		Targeting:    targeting,
 	}, nil
 }
 
-// Resolve разрешает короткий код в оригинальный URL.
-func (s *URLService) Resolve(ctx context.Context, shortCode string) (string, error) {
-	url, err := s.repo.FindByShortURL(ctx, shortCode)
+// result формирует ответ по сохранённой записи.
+func (s *URLService) result(url *model.URL) *ShortenResult {
+	res := &ShortenResult{
+		ShortURL:     s.baseURL + "/" + url.ShortURL,
+		ExpiresAt:    url.ExpiresAt,
+		NotBefore:    url.NotBefore,
+		NotAfter:     url.NotAfter,
+		MaxClicks:    url.MaxClicks,
+		RedirectType: url.RedirectType,
+	}
+	if url.Targeting != nil {
+		res.DeviceTargets = url.Targeting.Devices
+		res.CountryTargets = url.Targeting.Countries
+		res.Schedule = url.Targeting.Schedule
+		res.Variants = url.Targeting.Variants
+	}
+	return res
+}
+
+// expiryFromParams вычисляет момент истечения ссылки из ExpiresAt или TTL.
+// Возвращает nil для бессрочной ссылки и ErrInvalidExpiry, если срок уже прошёл.
+func expiryFromParams(params ShortenParams, now time.Time) (*time.Time, error) {
+	switch {
+	case params.ExpiresAt != nil && params.TTL != 0:
+		return nil, ErrInvalidExpiry
+	case params.ExpiresAt != nil:
+		if !params.ExpiresAt.After(now) {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt := params.ExpiresAt.UTC()
+		return &expiresAt, nil
+	case params.TTL < 0:
+		return nil, ErrInvalidExpiry
+	case params.TTL > 0:
+		expiresAt := now.Add(params.TTL).UTC()
+		return &expiresAt, nil
+	}
+	return nil, nil
+}
+
+// activationWindow проверяет окно активности: NotBefore раньше NotAfter,
+// а NotAfter ещё не наступил. Иначе возвращает ErrInvalidSchedule.
+func activationWindow(params ShortenParams, now time.Time) (notBefore, notAfter *time.Time, err error) {
+	if params.NotAfter != nil && !params.NotAfter.After(now) {
+		return nil, nil, ErrInvalidSchedule
+	}
+	notBefore, notAfter = utcTime(params.NotBefore), utcTime(params.NotAfter)
+	if notBefore != nil && notAfter != nil && !notBefore.Before(*notAfter) {
+		return nil, nil, ErrInvalidSchedule
+	}
+	return notBefore, notAfter, nil
+}
+
+// Resolution — результат разрешения короткой ссылки.
+type Resolution struct {
+	URLID int64
+	// LongURL — оригинальный URL, выбранный для посетителя.
+	LongURL string
+	// Targeted — URL выбран по посетителю (устройство, страна, вариант A/B-теста).
+	Targeted bool
+	// Variant — вариант A/B-теста, выбранный для посетителя; пустой — теста нет
+	// или URL выбран по платформе или стране.
+	Variant string
+	// Counted — переход уже засчитан в click_count (ссылки с лимитом переходов
+	// считаются синхронно); остальные переходы засчитывает запись событий.
+	Counted bool
+	// RedirectType — HTTP-статус редиректа (301, 302, 307, 308).
+	RedirectType int
+	// ValidUntil — момент, когда ответ может измениться (истечение ссылки, конец окна
+	// активности, смена окна расписания): кэшировать постоянный редирект дольше нельзя.
+	ValidUntil *time.Time
+	// PasswordProtected — переход выполнен после ввода пароля.
+	PasswordProtected bool
+}
+
+// Resolve разрешает короткий код в оригинальный URL, выбранный для посетителя
+// по правилам ссылки (см. model.Targeting). Для отключённой ссылки возвращает ErrDisabled, с истёкшим сроком действия — ErrExpired,
+// до начала окна активности — ErrNotYetActive, после его конца — ErrExpired,
+// для ссылки с исчерпанным лимитом переходов — ErrClickLimitReached,
+// для защищённой паролем — ErrPasswordRequired (переход выполняется через Unlock).
+func (s *URLService) Resolve(ctx context.Context, shortCode string, visitor Visitor) (_ *Resolution, err error) {
+	ctx, span := startSpan(ctx, "URLService.Resolve", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	url, err := s.findActive(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if url.PasswordHash != "" {
+		return nil, ErrPasswordRequired
+	}
+	return s.follow(ctx, url, visitor)
+}
+
+// Unlock разрешает защищённую паролем ссылку.
+// Неверный пароль даёт ErrWrongPassword; после unlockMaxAttempts неверных попыток
+// с одного адреса (visitor.IP) проверка пароля для кода с этого адреса блокируется
+// на unlockWindow с ошибкой ErrTooManyAttempts.
+func (s *URLService) Unlock(
+	ctx context.Context,
+	shortCode, password string,
+	visitor Visitor,
+) (_ *Resolution, err error) {
+	ctx, span := startSpan(ctx, "URLService.Unlock", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	url, err := s.findActive(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if url.PasswordHash == "" {
+		return s.follow(ctx, url, visitor)
+	}
+
+	now := s.now()
+	if !s.attempts.Allow(shortCode, visitor.IP, now) {
+		return nil, ErrTooManyAttempts
+	}
+	if !checkPassword(url.PasswordHash, password) {
+		s.attempts.Fail(shortCode, visitor.IP, now)
+		return nil, ErrWrongPassword
+	}
+	s.attempts.Reset(shortCode, visitor.IP)
+
+	return s.follow(ctx, url, visitor)
+}
+
+// findActive ищет ссылку по коду и проверяет, что она не отключена, срок её действия
+// не истёк и текущий момент попадает в окно активности.
+func (s *URLService) findActive(ctx context.Context, shortCode string) (*model.URL, error) {
+	url, err := s.lookup(ctx, shortCode)
 	if err != nil {
-		return "", fmt.Errorf("сервис: разрешение url: %w", err)
+		return nil, err
 	}
 	if url == nil {
-		return "", ErrNotFound
+		s.metrics.NotFound()
+		return nil, ErrNotFound
+	}
+	if url.Disabled {
+		return nil, ErrDisabled
+	}
+	now := s.now()
+	if url.IsExpired(now) || url.NotAfter != nil && !now.Before(*url.NotAfter) {
+		return nil, ErrExpired
 	}
-	return url.LongURL, nil
+	if url.NotBefore != nil && now.Before(*url.NotBefore) {
+		return nil, ErrNotYetActive
+	}
+	return url, nil
+}
+
+// lookup ищет ссылку сначала в кэше, затем в БД, и кэширует результат,
+// в том числе отсутствие кода. Кэшированная запись безопасна для редиректа:
+// отключение, срок действия и пароль проверяются по ней заново, лимит переходов
+// списывается в БД, а изменения ссылки через сервис сбрасывают запись (см. invalidate).
+func (s *URLService) lookup(ctx context.Context, shortCode string) (*model.URL, error) {
+	if url, ok := s.cache.Get(ctx, shortCode); ok {
+		return url, nil
+	}
+
+	version := s.cache.Version(ctx, shortCode)
+	url, err := s.repo.FindByShortURL(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("сервис: разрешение url: %w", err)
+	}
+	s.cache.Fill(ctx, shortCode, url, version)
+
+	return url, nil
+}
+
+// invalidate удаляет ссылку из кэша после её изменения. При включённом Redis
+// удаление доходит и до локальных кэшей остальных реплик.
+func (s *URLService) invalidate(ctx context.Context, shortCode string) {
+	s.cache.Delete(ctx, shortCode)
+}
+
+// CacheStats возвращает счётчики кэша ссылок.
+func (s *URLService) CacheStats() cache.Stats {
+	return s.cache.Stats()
+}
+
+// follow возвращает результат перехода по ссылке. Переход по ссылке с лимитом
+// засчитывается сразу: атомарно в БД, поэтому лимит соблюдается и при параллельных
+// редиректах с нескольких реплик.
+func (s *URLService) follow(ctx context.Context, url *model.URL, visitor Visitor) (*Resolution, error) {
+	now := s.now()
+	longURL, variant := destination(url, visitor, now)
+	res := &Resolution{
+		URLID:             url.ID,
+		LongURL:           longURL,
+		Targeted:          url.Targeting.DependsOnVisitor(),
+		Variant:           variant,
+		RedirectType:      url.RedirectType,
+		ValidUntil:        validUntil(url, now),
+		PasswordProtected: url.PasswordHash != "",
+	}
+	// Записи, закэшированные в Redis до появления типа редиректа, его не содержат
+	if res.RedirectType == 0 {
+		res.RedirectType = http.StatusFound
+	}
+
+	if url.MaxClicks != nil {
+		ok, err := s.repo.ConsumeClick(ctx, url.ID)
+		if err != nil {
+			return nil, fmt.Errorf("сервис: учёт перехода: %w", err)
+		}
+		if !ok {
+			return nil, ErrClickLimitReached
+		}
+		res.Counted = true
+	}
+	s.metrics.Redirected()
+
+	return res, nil
 }
 
 // HealthCheck проверяет подключение к базе данных.
@@ -80,5 +493,45 @@ func (s *URLService) HealthCheck(ctx context.Context) error {
 	return s.repo.Ping(ctx)
 }
 
-// ErrNotFound — ошибка: URL не найден.
-var ErrNotFound = fmt.Errorf("url не найден")
+var (
+	// ErrNotFound — ошибка: URL не найден.
+	ErrNotFound = fmt.Errorf("url не найден")
+	// ErrVersionNotFound — ошибка: в истории ссылки нет указанной версии.
+	ErrVersionNotFound = fmt.Errorf("версия не найдена")
+	// ErrInvalidStatsQuery — ошибка: некорректный интервал или параметры статистики.
+	ErrInvalidStatsQuery = fmt.Errorf("некорректные параметры статистики")
+	// ErrInvalidAlias — ошибка: пользовательский код не проходит проверку.
+	ErrInvalidAlias = fmt.Errorf("недопустимый код короткой ссылки")
+	// ErrAliasTaken — ошибка: пользовательский код уже занят другой ссылкой.
+	ErrAliasTaken = fmt.Errorf("код короткой ссылки уже занят")
+	// ErrInvalidExpiry — ошибка: срок действия задан некорректно или уже прошёл.
+	ErrInvalidExpiry = fmt.Errorf("некорректный срок действия ссылки")
+	// ErrDisabled — ошибка: ссылка отключена.
+	ErrDisabled = fmt.Errorf("ссылка отключена")
+	// ErrExpired — ошибка: срок действия ссылки истёк.
+	ErrExpired = fmt.Errorf("срок действия ссылки истёк")
+	// ErrInvalidMaxClicks — ошибка: лимит переходов должен быть положительным.
+	ErrInvalidMaxClicks = fmt.Errorf("некорректный лимит переходов")
+	// ErrClickLimitReached — ошибка: лимит переходов по ссылке исчерпан.
+	ErrClickLimitReached = fmt.Errorf("лимит переходов исчерпан")
+	// ErrInvalidRedirectType — ошибка: недопустимый тип редиректа.
+	ErrInvalidRedirectType = fmt.Errorf("недопустимый тип редиректа")
+	// ErrInvalidSchedule — ошибка: некорректное окно активности или расписание.
+	ErrInvalidSchedule = fmt.Errorf("некорректное окно активности или расписание")
+	// ErrNotYetActive — ошибка: окно активности ссылки ещё не началось.
+	ErrNotYetActive = fmt.Errorf("ссылка ещё не активна")
+	// ErrBlockedDomain — ошибка: ссылка ведёт на заблокированный домен.
+	ErrBlockedDomain = fmt.Errorf("домен заблокирован")
+	// ErrInvalidDomain — ошибка: некорректное имя домена.
+	ErrInvalidDomain = fmt.Errorf("некорректный домен")
+	// ErrInvalidTargeting — ошибка: неизвестная платформа или некорректный URL в правилах ссылки.
+	ErrInvalidTargeting = fmt.Errorf("некорректные правила выбора url")
+	// ErrInvalidPassword — ошибка: пароль ссылки не проходит проверку длины.
+	ErrInvalidPassword = fmt.Errorf("некорректный пароль ссылки")
+	// ErrPasswordRequired — ошибка: для перехода по ссылке нужен пароль.
+	ErrPasswordRequired = fmt.Errorf("ссылка защищена паролем")
+	// ErrWrongPassword — ошибка: введён неверный пароль.
+	ErrWrongPassword = fmt.Errorf("неверный пароль")
+	// ErrTooManyAttempts — ошибка: слишком много неверных попыток ввода пароля.
+	ErrTooManyAttempts = fmt.Errorf("слишком много попыток ввода пароля")
+)
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey — ключ спана в настройках выполняемого запроса GORM.
+const spanKey = "tracing:span"
+
+var dbTracer = otel.Tracer("tinyurl/internal/db")
+
+// InstrumentDB подключает к GORM спаны для каждого SQL-запроса. Спан становится
+// дочерним для спана из контекста запроса (db.WithContext), поэтому вызовы
+// репозитория видны внутри спанов сервиса. system — db.system.name (postgresql, sqlite).
+func InstrumentDB(db *gorm.DB, system string) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation     string
+		before, after func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, h := range hooks {
+		name := "gorm." + h.operation
+		if err := h.before("tracing:before_"+h.operation, func(tx *gorm.DB) {
+			ctx, span := dbTracer.Start(tx.Statement.Context, name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attribute.String("db.system.name", system)),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet(spanKey, span)
+		}); err != nil {
+			return err
+		}
+
+		if err := h.after("tracing:after_"+h.operation, func(tx *gorm.DB) {
+			v, ok := tx.InstanceGet(spanKey)
+			if !ok {
+				return
+			}
+			span := v.(trace.Span)
+			defer span.End()
+
+			// Текст запроса без значений параметров: они могут содержать пароли и URL
+			span.SetAttributes(
+				attribute.String("db.query.text", tx.Statement.SQL.String()),
+				attribute.String("db.collection.name", tx.Statement.Table),
+				attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+			)
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				span.RecordError(tx.Error)
+				span.SetStatus(codes.Error, tx.Error.Error())
+			}
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
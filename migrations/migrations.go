@@ -0,0 +1,8 @@
+// Package migrations содержит SQL-миграции схемы, встроенные в бинарный файл.
+// Каталог соответствует драйверу хранилища: postgres или sqlite.
+package migrations
+
+import "embed"
+
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
@@ -0,0 +1,5 @@
+-- Срок действия ссылки (NULL — бессрочная)
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+-- Индекс для поиска истёкших ссылок
+CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls (expires_at) WHERE expires_at IS NOT NULL;
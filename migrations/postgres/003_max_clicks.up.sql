@@ -0,0 +1,3 @@
+-- Лимит переходов (NULL — без ограничений) и счётчик засчитанных переходов
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS max_clicks  BIGINT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS click_count BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- bcrypt-хеш пароля ссылки (пустая строка — ссылка без пароля)
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash VARCHAR(60) NOT NULL DEFAULT '';
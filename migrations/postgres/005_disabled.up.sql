@@ -0,0 +1,2 @@
+-- Ручное отключение ссылки
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,13 @@
+-- История изменений оригинального URL ссылки
+CREATE TABLE IF NOT EXISTS url_history (
+    id           BIGSERIAL PRIMARY KEY,
+    url_id       BIGINT NOT NULL REFERENCES urls (id) ON DELETE CASCADE,
+    version      INTEGER NOT NULL,
+    old_long_url TEXT NOT NULL,
+    new_long_url TEXT NOT NULL,
+    changed_by   VARCHAR(255) NOT NULL,
+    changed_at   TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Номер версии уникален в пределах ссылки
+CREATE UNIQUE INDEX IF NOT EXISTS idx_url_history_version ON url_history (url_id, version);
@@ -0,0 +1,14 @@
+-- События переходов по коротким ссылкам
+CREATE TABLE IF NOT EXISTS clicks (
+    id         BIGSERIAL PRIMARY KEY,
+    url_id     BIGINT NOT NULL,
+    short_url  VARCHAR(12) NOT NULL,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    referrer   TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_hash    VARCHAR(64) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT ''
+);
+
+-- Индекс для выборки переходов по ссылке за период
+CREATE INDEX IF NOT EXISTS idx_clicks_url_time ON clicks (url_id, clicked_at);
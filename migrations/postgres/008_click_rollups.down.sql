@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS click_dimensions;
+DROP TABLE IF EXISTS click_hourly;
+DROP INDEX IF EXISTS idx_clicks_pending;
+ALTER TABLE clicks DROP COLUMN IF EXISTS aggregated;
@@ -0,0 +1,23 @@
+-- Отметка об учёте события в агрегатах
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS aggregated BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Индекс для выборки ещё не агрегированных событий
+CREATE INDEX IF NOT EXISTS idx_clicks_pending ON clicks (id) WHERE NOT aggregated;
+
+-- Число переходов по ссылке за час (UTC)
+CREATE TABLE IF NOT EXISTS click_hourly (
+    url_id BIGINT NOT NULL,
+    hour   TIMESTAMPTZ NOT NULL,
+    clicks BIGINT NOT NULL,
+    PRIMARY KEY (url_id, hour)
+);
+
+-- Число переходов по ссылке за день (UTC) в разрезе источника, браузера, ОС и устройства
+CREATE TABLE IF NOT EXISTS click_dimensions (
+    url_id    BIGINT NOT NULL,
+    day       TIMESTAMPTZ NOT NULL,
+    dimension VARCHAR(16) NOT NULL,
+    value     VARCHAR(255) NOT NULL,
+    clicks    BIGINT NOT NULL,
+    PRIMARY KEY (url_id, day, dimension, value)
+);
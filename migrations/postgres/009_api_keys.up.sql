@@ -0,0 +1,11 @@
+-- Ключи доступа к API: хранится только SHA-256 ключа
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         BIGSERIAL PRIMARY KEY,
+    name       VARCHAR(100) NOT NULL,
+    prefix     VARCHAR(16) NOT NULL,
+    key_hash   VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_long_url ON urls (long_url);
@@ -0,0 +1,4 @@
+-- AutoMigrate создавал B-tree индекс idx_long_url рядом с hash-индексом из 001.
+-- B-tree не принимает значения длиннее ~2,7 КБ, поэтому остаётся только hash-индекс.
+DROP INDEX IF EXISTS idx_long_url;
+CREATE INDEX IF NOT EXISTS idx_urls_long_url ON urls USING hash (long_url);
@@ -0,0 +1,3 @@
+-- HTTP-статус редиректа ссылки
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_type SMALLINT NOT NULL DEFAULT 302
+    CHECK (redirect_type IN (301, 302, 307, 308));
@@ -0,0 +1,2 @@
+-- Правила выбора оригинального URL по посетителю (устройство и т. п.)
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS targeting JSONB;
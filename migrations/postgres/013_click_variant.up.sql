@@ -0,0 +1,2 @@
+-- Вариант A/B-теста, на который попал посетитель
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS variant VARCHAR(64) NOT NULL DEFAULT '';
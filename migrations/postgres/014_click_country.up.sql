@@ -0,0 +1,2 @@
+-- Страна посетителя по базе GeoIP
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS country VARCHAR(2) NOT NULL DEFAULT '';
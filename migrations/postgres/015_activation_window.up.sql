@@ -0,0 +1,3 @@
+-- Окно активности ссылки
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS not_before TIMESTAMPTZ;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS not_after TIMESTAMPTZ;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS click_dimensions;
+DROP TABLE IF EXISTS click_hourly;
+DROP TABLE IF EXISTS clicks;
+DROP TABLE IF EXISTS url_history;
+DROP TABLE IF EXISTS urls;
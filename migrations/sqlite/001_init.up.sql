@@ -0,0 +1,72 @@
+-- Полная схема для SQLite (соответствует migrations/postgres/001–010).
+-- Время хранится строками в UTC, логические значения — целыми 0/1.
+
+CREATE TABLE IF NOT EXISTS urls (
+    id            INTEGER PRIMARY KEY,
+    short_url     TEXT NOT NULL,
+    long_url      TEXT NOT NULL,
+    created_at    DATETIME,
+    expires_at    DATETIME,
+    max_clicks    INTEGER,
+    click_count   INTEGER NOT NULL DEFAULT 0,
+    password_hash TEXT NOT NULL DEFAULT '',
+    disabled      NUMERIC NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_short_url ON urls (short_url);
+CREATE INDEX IF NOT EXISTS idx_long_url ON urls (long_url);
+CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls (expires_at);
+
+CREATE TABLE IF NOT EXISTS url_history (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    url_id       INTEGER NOT NULL REFERENCES urls (id) ON DELETE CASCADE,
+    version      INTEGER NOT NULL,
+    old_long_url TEXT NOT NULL,
+    new_long_url TEXT NOT NULL,
+    changed_by   TEXT NOT NULL,
+    changed_at   DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_url_history_version ON url_history (url_id, version);
+
+CREATE TABLE IF NOT EXISTS clicks (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    url_id     INTEGER NOT NULL,
+    short_url  TEXT NOT NULL,
+    clicked_at DATETIME NOT NULL,
+    referrer   TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_hash    TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    aggregated NUMERIC NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS idx_clicks_url_time ON clicks (url_id, clicked_at);
+CREATE INDEX IF NOT EXISTS idx_clicks_pending ON clicks (id) WHERE NOT aggregated;
+
+CREATE TABLE IF NOT EXISTS click_hourly (
+    url_id INTEGER NOT NULL,
+    hour   DATETIME NOT NULL,
+    clicks INTEGER NOT NULL,
+    PRIMARY KEY (url_id, hour)
+);
+
+CREATE TABLE IF NOT EXISTS click_dimensions (
+    url_id    INTEGER NOT NULL,
+    day       DATETIME NOT NULL,
+    dimension TEXT NOT NULL,
+    value     TEXT NOT NULL,
+    clicks    INTEGER NOT NULL,
+    PRIMARY KEY (url_id, day, dimension, value)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL,
+    prefix     TEXT NOT NULL,
+    key_hash   TEXT NOT NULL,
+    created_at DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
@@ -0,0 +1,3 @@
+-- HTTP-статус редиректа ссылки
+ALTER TABLE urls ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 302
+    CHECK (redirect_type IN (301, 302, 307, 308));
@@ -0,0 +1,2 @@
+-- Вариант A/B-теста, на который попал посетитель
+ALTER TABLE clicks ADD COLUMN variant TEXT NOT NULL DEFAULT '';
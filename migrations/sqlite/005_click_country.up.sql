@@ -0,0 +1,2 @@
+-- Страна посетителя по базе GeoIP
+ALTER TABLE clicks ADD COLUMN country TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Окно активности ссылки
+ALTER TABLE urls ADD COLUMN not_before DATETIME;
+ALTER TABLE urls ADD COLUMN not_after DATETIME;
@@ -0,0 +1,2 @@
+-- Индекс для поиска ссылок с завершившимся окном активности
+CREATE INDEX IF NOT EXISTS idx_urls_not_after ON urls (not_after);
@@ -0,0 +1,122 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Level — уровень коррекции ошибок QR-кода.
+type Level string
+
+// Уровни коррекции ошибок: восстанавливается примерно 7%, 15%, 25% и 30% данных.
+const (
+	LevelL Level = "L"
+	LevelM Level = "M"
+	LevelQ Level = "Q"
+	LevelH Level = "H"
+)
+
+var levels = map[Level]qrcode.RecoveryLevel{
+	LevelL: qrcode.Low,
+	LevelM: qrcode.Medium,
+	LevelQ: qrcode.High,
+	LevelH: qrcode.Highest,
+}
+
+// ParseLevel разбирает уровень коррекции ошибок без учёта регистра.
+func ParseLevel(s string) (Level, bool) {
+	l := Level(strings.ToUpper(s))
+	_, ok := levels[l]
+	return l, ok
+}
+
+// Code — матрица модулей QR-кода без полей.
+type Code struct {
+	modules [][]bool
+}
+
+// Encode кодирует строку в QR-код с заданным уровнем коррекции ошибок.
+func Encode(content string, level Level) (*Code, error) {
+	recovery, ok := levels[level]
+	if !ok {
+		return nil, fmt.Errorf("qr: неизвестный уровень коррекции %q", level)
+	}
+
+	q, err := qrcode.New(content, recovery)
+	if err != nil {
+		return nil, fmt.Errorf("qr: ошибка кодирования: %w", err)
+	}
+	q.DisableBorder = true
+
+	return &Code{modules: q.Bitmap()}, nil
+}
+
+// scale возвращает размер модуля в пикселях и полную сторону изображения
+// так, чтобы код с полями margin (в модулях) уместился в size пикселей.
+func (c *Code) scale(size, margin int) (int, int) {
+	total := len(c.modules) + 2*margin
+	module := max(size/total, 1)
+	return module, module * total
+}
+
+// PNG рисует QR-код в PNG со стороной не больше size пикселей
+// (если код не помещается, модуль занимает 1 пиксель) и полями margin модулей.
+func (c *Code) PNG(size, margin int) ([]byte, error) {
+	module, side := c.scale(size, margin)
+
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y, row := range c.modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0, y0 := (x+margin)*module, (y+margin)*module
+			for dy := 0; dy < module; dy++ {
+				for dx := 0; dx < module; dx++ {
+					img.SetColorIndex(x0+dx, y0+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("qr: ошибка кодирования png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG рисует QR-код в SVG со стороной size пикселей и полями margin модулей.
+// Тёмные модули объединяются в один path, поэтому документ остаётся компактным.
+func (c *Code) SVG(size, margin int) []byte {
+	total := len(c.modules) + 2*margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, total, total,
+	)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, total, total)
+	for y, row := range c.modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// Горизонтальная серия тёмных модулей рисуется одним прямоугольником
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start+margin, y+margin, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
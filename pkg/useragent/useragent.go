@@ -0,0 +1,102 @@
+package useragent
+
+import "strings"
+
+// Названия браузеров, ОС и классов устройств, возвращаемые Parse.
+const (
+	Unknown = "unknown"
+
+	BrowserChrome  = "Chrome"
+	BrowserEdge    = "Edge"
+	BrowserFirefox = "Firefox"
+	BrowserOpera   = "Opera"
+	BrowserSafari  = "Safari"
+	BrowserYandex  = "Yandex"
+
+	OSAndroid = "Android"
+	OSIOS     = "iOS"
+	OSLinux   = "Linux"
+	OSMacOS   = "macOS"
+	OSWindows = "Windows"
+
+	DeviceBot     = "bot"
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+// Info — сведения о клиенте, извлечённые из заголовка User-Agent.
+type Info struct {
+	Browser string
+	OS      string
+	Device  string
+}
+
+// botMarkers — подстроки, по которым опознаются поисковые роботы и HTTP-клиенты.
+var botMarkers = []string{"bot", "crawler", "spider", "curl/", "wget/", "python-requests", "go-http-client"}
+
+// Parse определяет браузер, ОС и класс устройства по строке User-Agent.
+// Разбор эвристический: проверяются характерные подстроки в порядке от частных к общим.
+func Parse(ua string) Info {
+	if ua == "" {
+		return Info{Browser: Unknown, OS: Unknown, Device: Unknown}
+	}
+	lower := strings.ToLower(ua)
+
+	return Info{
+		Browser: parseBrowser(lower),
+		OS:      parseOS(lower),
+		Device:  parseDevice(lower),
+	}
+}
+
+func parseBrowser(ua string) string {
+	switch {
+	case strings.Contains(ua, "yabrowser/"):
+		return BrowserYandex
+	case strings.Contains(ua, "edg/"), strings.Contains(ua, "edge/"):
+		return BrowserEdge
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		return BrowserOpera
+	case strings.Contains(ua, "firefox/"), strings.Contains(ua, "fxios/"):
+		return BrowserFirefox
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"):
+		return BrowserChrome
+	case strings.Contains(ua, "safari/"):
+		return BrowserSafari
+	}
+	return Unknown
+}
+
+func parseOS(ua string) string {
+	switch {
+	case strings.Contains(ua, "android"):
+		return OSAndroid
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return OSIOS
+	case strings.Contains(ua, "windows"):
+		return OSWindows
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		return OSMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		return OSLinux
+	}
+	return Unknown
+}
+
+func parseDevice(ua string) string {
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return DeviceBot
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		return DeviceMobile
+	}
+	return DeviceDesktop
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tinyurl/internal/analytics"
+	"tinyurl/internal/handler"
+	"tinyurl/internal/model"
+	"tinyurl/internal/service"
+)
+
+func TestSummarize(t *testing.T) {
+	base := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+	chrome := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+
+	hourly, dims := analytics.Summarize([]model.Click{
+		{URLID: 1, ClickedAt: base, Referrer: "https://www.google.com/search?q=x", UserAgent: chrome},
+		{URLID: 1, ClickedAt: base.Add(20 * time.Minute), UserAgent: chrome},
+		{URLID: 1, ClickedAt: base.Add(time.Hour), Variant: "b", Country: "DE"},
+	})
+
+	if len(hourly) != 2 {
+		t.Fatalf("почасовых агрегатов = %d, ожидалось 2", len(hourly))
+	}
+	if !hourly[0].Hour.Equal(base.Truncate(time.Hour)) || hourly[0].Clicks != 2 {
+		t.Errorf("первый час = %+v, ожидалось 2 перехода в %v", hourly[0], base.Truncate(time.Hour))
+	}
+
+	counts := make(map[string]int64)
+	for _, d := range dims {
+		counts[d.Dimension+"="+d.Value] += d.Clicks
+	}
+	want := map[string]int64{
+		"referrer=google.com": 1,
+		"referrer=direct":     2,
+		"browser=Chrome":      2,
+		"os=Windows":          2,
+		"device=desktop":      2,
+		"device=unknown":      1,
+		"variant=b":           1,
+		"country=DE":          1,
+	}
+	if counts["variant="] != 0 || counts["country="] != 0 {
+		t.Error("переходы без варианта и страны не должны попадать в разбивки по ним")
+	}
+	for k, n := range want {
+		if counts[k] != n {
+			t.Errorf("%s = %d, ожидалось %d", k, counts[k], n)
+		}
+	}
+}
+
+// mockStatsService — мок сервиса статистики.
+type mockStatsService struct {
+	statsFn func(ctx context.Context, shortCode string, q service.StatsQuery) (*service.Stats, error)
+}
+
+func (m *mockStatsService) Stats(ctx context.Context, shortCode string, q service.StatsQuery) (*service.Stats, error) {
+	return m.statsFn(ctx, shortCode, q)
+}
+
+func TestStatsHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   error
+		want  int
+	}{
+		{"успех", "?granularity=hour&top=5", nil, http.StatusOK},
+		{"некорректная_дата", "?from=вчера", nil, http.StatusBadRequest},
+		{"некорректный_интервал", "?granularity=week", service.ErrInvalidStatsQuery, http.StatusBadRequest},
+		{"не_найдена", "", service.ErrNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockStatsService{
+				statsFn: func(_ context.Context, code string, q service.StatsQuery) (*service.Stats, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &service.Stats{Code: code, Granularity: q.Granularity}, nil
+				},
+			}
+			h := handler.NewStatsHandler(mock)
+
+			rec := httptest.NewRecorder()
+			h.Stats(rec, chiRequest(http.MethodGet, "/api/v1/urls/abc123/stats"+tt.query, "code", "abc123"))
+
+			if rec.Code != tt.want {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
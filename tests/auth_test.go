@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tinyurl/internal/middleware"
+	"tinyurl/internal/service"
+)
+
+type mockAuthenticator struct {
+	keys map[string]string
+	err  error
+}
+
+func (m *mockAuthenticator) Authenticate(_ context.Context, key string) (*service.Caller, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	name, ok := m.keys[key]
+	if !ok {
+		return nil, service.ErrInvalidAPIKey
+	}
+	return &service.Caller{KeyID: 1, Name: name}, nil
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		required   bool
+		header     string
+		authErr    error
+		want       int
+		wantCaller string
+	}{
+		{"валидный_ключ", true, "Bearer tu_good", nil, http.StatusOK, "marketing"},
+		{"схема_без_учёта_регистра", true, "bearer tu_good", nil, http.StatusOK, "marketing"},
+		{"без_ключа_обязательно", true, "", nil, http.StatusUnauthorized, ""},
+		{"без_ключа_необязательно", false, "", nil, http.StatusOK, ""},
+		{"неизвестный_ключ", false, "Bearer tu_bad", nil, http.StatusUnauthorized, ""},
+		{"не_bearer", true, "Basic dXNlcjpwYXNz", nil, http.StatusUnauthorized, ""},
+		{"ошибка_проверки", true, "Bearer tu_good", errors.New("db down"), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authn := &mockAuthenticator{keys: map[string]string{"tu_good": "marketing"}, err: tt.authErr}
+
+			var gotCaller string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if c := middleware.CallerFrom(r.Context()); c != nil {
+					gotCaller = c.Name
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			middleware.Auth(authn, tt.required)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("статус = %d, ожидался %d", rec.Code, tt.want)
+			}
+			if gotCaller != tt.wantCaller {
+				t.Errorf("владелец ключа = %q, ожидался %q", gotCaller, tt.wantCaller)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("ожидался заголовок WWW-Authenticate")
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tinyurl/internal/blocklist"
+)
+
+// openBlocklist записывает список в файл и загружает его.
+func openBlocklist(t *testing.T, content string) (*blocklist.List, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("ошибка записи списка: %v", err)
+	}
+	list, err := blocklist.Open(path, 0)
+	if err != nil {
+		t.Fatalf("ошибка загрузки списка: %v", err)
+	}
+	return list, path
+}
+
+func TestBlocklist_Blocked(t *testing.T) {
+	list, _ := openBlocklist(t, `
+# фишинг
+evil.example
+!safe.evil.example   # исключение для поддомена
+пример.рф
+xn--80ak6aa92e.com
+`)
+
+	tests := []struct {
+		name string
+		host string
+		want bool
+	}{
+		{"домен из списка", "evil.example", true},
+		{"поддомен", "login.evil.example", true},
+		{"регистр и точка в конце", "Login.EVIL.example.", true},
+		{"исключение", "safe.evil.example", false},
+		{"поддомен исключения", "cdn.safe.evil.example", false},
+		{"похожий домен", "notevil.example", false},
+		{"unicode из списка в punycode", "xn--e1afmkfd.xn--p1ai", true},
+		{"punycode из списка в unicode", "аррӏе.com", true},
+		{"другой домен", "example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := list.Blocked(tt.host); got != tt.want {
+				t.Errorf("Blocked(%q) = %v, ожидалось %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlocklist_AllowlistMode(t *testing.T) {
+	list, _ := openBlocklist(t, "*\n!example.com\n")
+
+	if list.Blocked("docs.example.com") {
+		t.Error("разрешённый домен не должен блокироваться")
+	}
+	if !list.Blocked("example.org") {
+		t.Error("при правиле '*' домен без разрешения должен блокироваться")
+	}
+}
+
+func TestBlocklist_Reload(t *testing.T) {
+	list, path := openBlocklist(t, "evil.example\n")
+
+	if reloaded, err := list.Reload(); reloaded || err != nil {
+		t.Errorf("Reload без изменений = %v, %v, ожидалось (false, nil)", reloaded, err)
+	}
+
+	// Время изменения сдвигается, чтобы не зависеть от точности ФС
+	touch := func(offset time.Duration) {
+		later := time.Now().Add(offset)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatalf("ошибка изменения времени файла: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("phish.example\n"), 0o644); err != nil {
+		t.Fatalf("ошибка записи списка: %v", err)
+	}
+	touch(time.Minute)
+	if reloaded, err := list.Reload(); !reloaded || err != nil {
+		t.Fatalf("Reload после замены = %v, %v, ожидалась перезагрузка", reloaded, err)
+	}
+	if list.Blocked("evil.example") || !list.Blocked("phish.example") {
+		t.Error("после перезагрузки должен действовать новый список")
+	}
+
+	// Список с ошибкой не заменяет рабочий
+	if err := os.WriteFile(path, []byte("https://bad.example/path\n"), 0o644); err != nil {
+		t.Fatalf("ошибка записи списка: %v", err)
+	}
+	touch(2 * time.Minute)
+	if _, err := list.Reload(); err == nil {
+		t.Error("Reload некорректного списка должен вернуть ошибку")
+	}
+	if !list.Blocked("phish.example") {
+		t.Error("после неудачной перезагрузки должен действовать прежний список")
+	}
+}
@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"tinyurl/internal/cache"
+	"tinyurl/internal/model"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := cache.NewLRU[string, int](2)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+	c.Get("a") // a становится самой свежей
+	c.Set("c", 3, time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b должна быть вытеснена")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("a = %d, %v, ожидалось 1, true", v, ok)
+	}
+	if c.Evictions() != 1 {
+		t.Errorf("вытеснений = %d, ожидалось 1", c.Evictions())
+	}
+}
+
+func TestLRU_TTL(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := cache.NewLRUWithClock[string, int](10, func() time.Time { return now })
+	c.Set("a", 1, time.Minute)
+
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Error("запись должна быть жива до истечения TTL")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Error("запись должна истечь")
+	}
+	if c.Len() != 0 {
+		t.Errorf("размер = %d, истёкшая запись должна удаляться", c.Len())
+	}
+}
+
+func TestLocalCache(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLocal(10, time.Minute, time.Minute)
+
+	if _, ok := c.Get(ctx, "abc"); ok {
+		t.Fatal("пустой кэш не должен давать попаданий")
+	}
+
+	c.Set(ctx, "abc", &model.URL{ShortURL: "abc", LongURL: "https://example.com"})
+	url, ok := c.Get(ctx, "abc")
+	if !ok || url == nil || url.LongURL != "https://example.com" {
+		t.Fatalf("Get = %+v, %v, ожидалась ссылка", url, ok)
+	}
+	url.LongURL = "https://changed.example.com"
+	if again, _ := c.Get(ctx, "abc"); again.LongURL != "https://example.com" {
+		t.Error("изменение полученной записи не должно менять кэш")
+	}
+
+	c.Set(ctx, "nope", nil)
+	if url, ok := c.Get(ctx, "nope"); !ok || url != nil {
+		t.Errorf("Get = %+v, %v, ожидалось закэшированное отсутствие", url, ok)
+	}
+
+	c.Delete(ctx, "abc")
+	if _, ok := c.Get(ctx, "abc"); ok {
+		t.Error("запись должна быть удалена")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 3 || stats.NegativeHits != 1 || stats.Misses != 2 {
+		t.Errorf("счётчики = %+v, ожидалось 3 попадания (1 отрицательное) и 2 промаха", stats)
+	}
+}
+
+func newRedisCache(t *testing.T) (*cache.Redis, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return cache.NewRedis(client, time.Hour, 30*time.Second, 100*time.Millisecond), mr
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newRedisCache(t)
+
+	maxClicks := int64(5)
+	c.Set(ctx, "abc", &model.URL{ID: 7, ShortURL: "abc", LongURL: "https://example.com", MaxClicks: &maxClicks, PasswordHash: "hash"})
+
+	url, ok := c.Get(ctx, "abc")
+	if !ok || url == nil {
+		t.Fatalf("Get = %+v, %v, ожидалась ссылка", url, ok)
+	}
+	if url.ID != 7 || url.PasswordHash != "hash" || url.MaxClicks == nil || *url.MaxClicks != 5 {
+		t.Errorf("ссылка = %+v, поля должны сохраниться", url)
+	}
+
+	c.Set(ctx, "nope", nil)
+	if url, ok := c.Get(ctx, "nope"); !ok || url != nil {
+		t.Errorf("Get = %+v, %v, ожидалось закэшированное отсутствие", url, ok)
+	}
+	mr.FastForward(31 * time.Second)
+	if _, ok := c.Get(ctx, "nope"); ok {
+		t.Error("отсутствие должно кэшироваться только negative_ttl")
+	}
+
+	c.Delete(ctx, "abc")
+	if _, ok := c.Get(ctx, "abc"); ok {
+		t.Error("запись должна быть удалена")
+	}
+}
+
+func TestRedisCache_FailOpen(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newRedisCache(t)
+	mr.Close()
+
+	c.Set(ctx, "abc", &model.URL{ShortURL: "abc"})
+	if _, ok := c.Get(ctx, "abc"); ok {
+		t.Error("при недоступном redis ожидался промах")
+	}
+	c.Delete(ctx, "abc")
+
+	if stats := c.Stats(); stats.Errors != 3 || stats.Misses != 1 {
+		t.Errorf("счётчики = %+v, ожидалось 3 ошибки и 1 промах", stats)
+	}
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	shared, _ := newRedisCache(t)
+	replicaA := cache.NewTiered(cache.NewLocal(10, time.Minute, time.Minute), shared)
+	replicaB := cache.NewTiered(cache.NewLocal(10, time.Minute, time.Minute), shared)
+
+	replicaA.Set(ctx, "abc", &model.URL{ShortURL: "abc", LongURL: "https://example.com"})
+
+	if url, ok := replicaB.Get(ctx, "abc"); !ok || url.LongURL != "https://example.com" {
+		t.Fatalf("Get = %+v, %v, вторая реплика должна найти ссылку в общем кэше", url, ok)
+	}
+	if url, ok := replicaB.Get(ctx, "abc"); !ok || url == nil {
+		t.Fatal("повторное чтение должно попасть в локальный уровень")
+	}
+
+	stats := replicaB.Stats()
+	if stats.Hits != 2 || stats.Misses != 0 || stats.Entries != 1 {
+		t.Errorf("счётчики = %+v, ожидалось 2 попадания без промахов и 1 локальная запись", stats)
+	}
+}
+
+func TestLocalCache_FillAfterDelete(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLocal(10, time.Minute, time.Minute)
+	stale := &model.URL{ShortURL: "abc", LongURL: "https://old.example.com"}
+
+	// Чтение из БД началось до изменения ссылки, а закончилось после инвалидации
+	v := c.Version(ctx, "abc")
+	c.Delete(ctx, "abc")
+	c.Fill(ctx, "abc", stale, v)
+	if _, ok := c.Get(ctx, "abc"); ok {
+		t.Error("устаревшая запись не должна попасть в кэш после инвалидации")
+	}
+
+	c.Fill(ctx, "abc", stale, c.Version(ctx, "abc"))
+	if _, ok := c.Get(ctx, "abc"); !ok {
+		t.Error("запись без конкурирующей инвалидации должна кэшироваться")
+	}
+}
+
+func TestRedisCache_FillAfterDelete(t *testing.T) {
+	ctx := context.Background()
+	replicaA, mr := newRedisCache(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	replicaB := cache.NewRedis(client, time.Hour, 30*time.Second, 100*time.Millisecond)
+	stale := &model.URL{ShortURL: "abc", LongURL: "https://old.example.com"}
+
+	v := replicaA.Version(ctx, "abc")
+	replicaB.Delete(ctx, "abc")
+	replicaA.Fill(ctx, "abc", stale, v)
+	if _, ok := replicaA.Get(ctx, "abc"); ok {
+		t.Error("устаревшая запись не должна перезаписать инвалидацию другой реплики")
+	}
+
+	replicaA.Fill(ctx, "abc", stale, replicaA.Version(ctx, "abc"))
+	if url, ok := replicaB.Get(ctx, "abc"); !ok || url == nil || url.LongURL != "https://old.example.com" {
+		t.Errorf("Get = %+v, %v, ожидалась запись после Fill с актуальной версией", url, ok)
+	}
+}
+
+func TestTieredCache_InvalidatesOtherReplicas(t *testing.T) {
+	ctx := context.Background()
+	sharedA, mr := newRedisCache(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	sharedB := cache.NewRedis(client, time.Hour, 30*time.Second, 100*time.Millisecond)
+
+	localB := cache.NewLocal(10, time.Minute, time.Minute)
+	subscription := sharedB.Subscribe(ctx, func(code string) { localB.Delete(ctx, code) })
+	t.Cleanup(func() { subscription.Close() })
+	replicaA := cache.NewTiered(cache.NewLocal(10, time.Minute, time.Minute), sharedA)
+	replicaB := cache.NewTiered(localB, sharedB)
+
+	for deadline := time.Now().Add(time.Second); mr.PubSubNumSub("tinyurl:invalidate")["tinyurl:invalidate"] == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("подписка на инвалидации не установлена")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	replicaA.Set(ctx, "abc", &model.URL{ShortURL: "abc", LongURL: "https://example.com"})
+	if _, ok := replicaB.Get(ctx, "abc"); !ok {
+		t.Fatal("вторая реплика должна найти ссылку в общем кэше")
+	}
+
+	replicaA.Delete(ctx, "abc")
+	for deadline := time.Now().Add(time.Second); ; {
+		if _, ok := localB.Get(ctx, "abc"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("локальный кэш второй реплики должен сброситься после удаления на первой")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRedisCache_FillWithoutTTL(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	c := cache.NewRedis(client, 0, 30*time.Second, 100*time.Millisecond)
+
+	c.Fill(ctx, "abc", &model.URL{ShortURL: "abc", LongURL: "https://example.com"}, c.Version(ctx, "abc"))
+	if url, ok := c.Get(ctx, "abc"); !ok || url == nil || url.LongURL != "https://example.com" {
+		t.Fatalf("Get = %+v, %v, при ttl = 0 запись должна сохраняться без срока", url, ok)
+	}
+	if ttl := mr.TTL("tinyurl:url:abc"); ttl != 0 {
+		t.Errorf("TTL записи = %s, ожидалась запись без срока", ttl)
+	}
+	if stats := c.Stats(); stats.Errors != 0 {
+		t.Errorf("ошибок = %d, заполнение при ttl = 0 не должно давать ошибок", stats.Errors)
+	}
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"tinyurl/internal/clicks"
+	"tinyurl/internal/model"
+)
+
+// fakeClickRepo — хранилище переходов в памяти.
+type fakeClickRepo struct {
+	mu     sync.Mutex
+	clicks []model.Click
+	counts map[int64]int64
+}
+
+func (f *fakeClickRepo) SaveBatch(_ context.Context, clicks []model.Click, counts map[int64]int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.clicks = append(f.clicks, clicks...)
+	if f.counts == nil {
+		f.counts = make(map[int64]int64)
+	}
+	for id, n := range counts {
+		f.counts[id] += n
+	}
+	return nil
+}
+
+func TestRecorder_DropsWhenQueueFull(t *testing.T) {
+	r := clicks.NewRecorder(&fakeClickRepo{}, clicks.Config{QueueSize: 2})
+
+	// Запись не запущена, поэтому очередь не разбирается
+	for i := 0; i < 5; i++ {
+		r.Record(clicks.Event{URLID: 1})
+	}
+
+	if got := r.Dropped(); got != 3 {
+		t.Errorf("Dropped() = %d, ожидалось 3", got)
+	}
+}
+
+func TestRecorder_FlushesOnStop(t *testing.T) {
+	repo := &fakeClickRepo{}
+	r := clicks.NewRecorder(repo, clicks.Config{BatchSize: 100, FlushInterval: time.Hour, IPSalt: "соль"})
+	r.Start()
+
+	r.Record(clicks.Event{URLID: 1, ShortURL: "abc", IP: "203.0.113.7"})
+	r.Record(clicks.Event{URLID: 1, ShortURL: "abc"})
+	r.Record(clicks.Event{URLID: 2, ShortURL: "lim", Counted: true})
+	r.Stop()
+
+	if len(repo.clicks) != 3 {
+		t.Fatalf("сохранено событий = %d, ожидалось 3", len(repo.clicks))
+	}
+	if got := repo.clicks[0].IPHash; got != clicks.HashIP("соль", "203.0.113.7") || got == "203.0.113.7" {
+		t.Errorf("IPHash = %q, ожидался хеш адреса", got)
+	}
+	if repo.counts[1] != 2 {
+		t.Errorf("click_count ссылки 1 увеличен на %d, ожидалось 2", repo.counts[1])
+	}
+	if _, ok := repo.counts[2]; ok {
+		t.Error("уже засчитанный переход учтён повторно")
+	}
+}
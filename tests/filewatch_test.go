@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tinyurl/internal/filewatch"
+)
+
+func TestFilewatch_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatalf("ошибка записи файла: %v", err)
+	}
+
+	var current string
+	broken := false
+	w := filewatch.New("test", path, 0, func(data []byte) error {
+		if broken {
+			return errors.New("некорректное содержимое")
+		}
+		current = string(data)
+		return nil
+	})
+
+	if changed, err := w.Reload(); err != nil || !changed || current != "v1" {
+		t.Fatalf("Reload = %v, %v, содержимое %q, ожидалась первая загрузка v1", changed, err, current)
+	}
+	if changed, err := w.Reload(); err != nil || changed {
+		t.Errorf("Reload = %v, %v, неизменённый файл не должен перечитываться", changed, err)
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.WriteFile(path, []byte("v2!"), 0o644); err != nil {
+		t.Fatalf("ошибка записи файла: %v", err)
+	}
+	os.Chtimes(path, later, later)
+	broken = true
+	if _, err := w.Reload(); err == nil || current != "v1" {
+		t.Errorf("ошибка = %v, содержимое %q, при ошибке загрузки должно остаться v1", err, current)
+	}
+
+	broken = false
+	if changed, err := w.Reload(); err != nil || !changed || current != "v2!" {
+		t.Errorf("Reload = %v, %v, содержимое %q, после исправления файл должен загрузиться повторно", changed, err, current)
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+
+	"tinyurl/internal/geoip"
+)
+
+// writeCountryDB записывает базу в формате GeoLite2-Country: подсеть → код страны.
+func writeCountryDB(t *testing.T, path string, networks map[string]string) {
+	t.Helper()
+
+	tree, err := mmdbwriter.New(mmdbwriter.Options{
+		DatabaseType:            "GeoLite2-Country",
+		RecordSize:              24,
+		IncludeReservedNetworks: true,
+	})
+	if err != nil {
+		t.Fatalf("ошибка создания базы: %v", err)
+	}
+	for cidr, country := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatalf("некорректная подсеть %s: %v", cidr, err)
+		}
+		record := mmdbtype.Map{"country": mmdbtype.Map{"iso_code": mmdbtype.String(country)}}
+		if err := tree.Insert(network, record); err != nil {
+			t.Fatalf("ошибка вставки %s: %v", cidr, err)
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("ошибка создания файла: %v", err)
+	}
+	defer f.Close()
+	if _, err := tree.WriteTo(f); err != nil {
+		t.Fatalf("ошибка записи базы: %v", err)
+	}
+}
+
+func TestGeoIP_Country(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	writeCountryDB(t, path, map[string]string{
+		"81.2.69.0/24":  "GB",
+		"2001:db8::/32": "DE",
+	})
+
+	db, err := geoip.Open(path, 0)
+	if err != nil {
+		t.Fatalf("ошибка загрузки базы: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ip   string
+		want string
+	}{
+		{"IPv4", "81.2.69.142", "GB"},
+		{"IPv4 в форме IPv6", "::ffff:81.2.69.142", "GB"},
+		{"IPv6", "2001:db8::1", "DE"},
+		{"нет в базе", "192.0.2.1", ""},
+		{"некорректный адрес", "не-адрес", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := db.Country(tt.ip); got != tt.want {
+				t.Errorf("Country(%q) = %q, ожидалась %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoIP_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	writeCountryDB(t, path, map[string]string{"81.2.69.0/24": "GB"})
+
+	db, err := geoip.Open(path, 0)
+	if err != nil {
+		t.Fatalf("ошибка загрузки базы: %v", err)
+	}
+	if reloaded, err := db.Reload(); reloaded || err != nil {
+		t.Errorf("Reload без изменений = %v, %v, ожидалось (false, nil)", reloaded, err)
+	}
+
+	// Новая версия базы: время изменения сдвигается, чтобы не зависеть от точности ФС
+	writeCountryDB(t, path, map[string]string{"81.2.69.0/24": "FR"})
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("ошибка изменения времени файла: %v", err)
+	}
+	if reloaded, err := db.Reload(); !reloaded || err != nil {
+		t.Fatalf("Reload после замены = %v, %v, ожидалась перезагрузка", reloaded, err)
+	}
+	if got := db.Country("81.2.69.142"); got != "FR" {
+		t.Errorf("Country = %q после перезагрузки, ожидалась FR", got)
+	}
+
+	// Повреждённый файл не заменяет рабочую базу
+	if err := os.WriteFile(path, []byte("не база"), 0o644); err != nil {
+		t.Fatalf("ошибка записи файла: %v", err)
+	}
+	if _, err := db.Reload(); err == nil {
+		t.Error("Reload повреждённого файла должен вернуть ошибку")
+	}
+	if got := db.Country("81.2.69.142"); got != "FR" {
+		t.Errorf("Country = %q после неудачной перезагрузки, ожидалась FR", got)
+	}
+}
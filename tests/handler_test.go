@@ -4,14 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"tinyurl/internal/cache"
+	"tinyurl/internal/clicks"
 	"tinyurl/internal/dto"
+	"tinyurl/internal/geoip"
 	"tinyurl/internal/handler"
 	"tinyurl/internal/service"
 )
@@ -19,23 +25,86 @@ import (
 // --- мок ---
 
 type mockURLService struct {
-	shortenFn     func(ctx context.Context, longURL string) (*service.ShortenResult, error)
-	resolveFn     func(ctx context.Context, shortCode string) (string, error)
+	// visitor — посетитель из последнего вызова Resolve.
+	visitor       service.Visitor
+	shortenFn     func(ctx context.Context, params service.ShortenParams) (*service.ShortenResult, error)
+	resolveFn     func(ctx context.Context, shortCode string) (*service.Resolution, error)
+	unlockFn      func(ctx context.Context, shortCode, password string) (*service.Resolution, error)
+	getFn         func(ctx context.Context, shortCode string) (*service.URLInfo, error)
+	updateFn      func(ctx context.Context, shortCode, longURL string) (*service.URLInfo, error)
+	historyFn     func(ctx context.Context, shortCode string) ([]service.HistoryEntry, error)
+	rollbackFn    func(ctx context.Context, shortCode string, version int) (*service.URLInfo, error)
+	setDisabledFn func(ctx context.Context, shortCode string, disabled bool) (*service.URLInfo, error)
+	deleteFn      func(ctx context.Context, shortCode string) error
 	healthCheckFn func(ctx context.Context) error
 }
 
-func (m *mockURLService) Shorten(ctx context.Context, longURL string) (*service.ShortenResult, error) {
+func (m *mockURLService) Shorten(ctx context.Context, params service.ShortenParams) (*service.ShortenResult, error) {
 	if m.shortenFn != nil {
-		return m.shortenFn(ctx, longURL)
+		return m.shortenFn(ctx, params)
 	}
 	return nil, errors.New("не реализовано")
 }
 
-func (m *mockURLService) Resolve(ctx context.Context, shortCode string) (string, error) {
+func (m *mockURLService) Resolve(ctx context.Context, shortCode string, visitor service.Visitor) (*service.Resolution, error) {
+	m.visitor = visitor
 	if m.resolveFn != nil {
 		return m.resolveFn(ctx, shortCode)
 	}
-	return "", errors.New("не реализовано")
+	return nil, errors.New("не реализовано")
+}
+
+func (m *mockURLService) Unlock(
+	ctx context.Context,
+	shortCode, password string,
+	_ service.Visitor,
+) (*service.Resolution, error) {
+	if m.unlockFn != nil {
+		return m.unlockFn(ctx, shortCode, password)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+func (m *mockURLService) Get(ctx context.Context, shortCode string) (*service.URLInfo, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, shortCode)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+func (m *mockURLService) UpdateDestination(ctx context.Context, shortCode, longURL string) (*service.URLInfo, error) {
+	if m.updateFn != nil {
+		return m.updateFn(ctx, shortCode, longURL)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+func (m *mockURLService) History(ctx context.Context, shortCode string) ([]service.HistoryEntry, error) {
+	if m.historyFn != nil {
+		return m.historyFn(ctx, shortCode)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+func (m *mockURLService) Rollback(ctx context.Context, shortCode string, version int) (*service.URLInfo, error) {
+	if m.rollbackFn != nil {
+		return m.rollbackFn(ctx, shortCode, version)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+func (m *mockURLService) SetDisabled(ctx context.Context, shortCode string, disabled bool) (*service.URLInfo, error) {
+	if m.setDisabledFn != nil {
+		return m.setDisabledFn(ctx, shortCode, disabled)
+	}
+	return nil, errors.New("не реализовано")
+}
+
+func (m *mockURLService) Delete(ctx context.Context, shortCode string) error {
+	if m.deleteFn != nil {
+		return m.deleteFn(ctx, shortCode)
+	}
+	return errors.New("не реализовано")
 }
 
 func (m *mockURLService) HealthCheck(ctx context.Context) error {
@@ -45,6 +114,25 @@ func (m *mockURLService) HealthCheck(ctx context.Context) error {
 	return errors.New("не реализовано")
 }
 
+func (m *mockURLService) CacheStats() cache.Stats {
+	return cache.Stats{}
+}
+
+// mockClickRecorder — приёмник переходов, запоминающий события.
+type mockClickRecorder struct {
+	events []clicks.Event
+}
+
+func (m *mockClickRecorder) Record(ev clicks.Event) bool {
+	m.events = append(m.events, ev)
+	return true
+}
+
+// fixedCountry — определение страны, возвращающее одну и ту же страну для любого адреса.
+type fixedCountry string
+
+func (c fixedCountry) Country(string) string { return string(c) }
+
 // --- вспомогательные функции ---
 
 func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v any) {
@@ -65,7 +153,7 @@ func chiRequest(method, path, paramKey, paramVal string) *http.Request {
 
 func TestShorten_Success(t *testing.T) {
 	mock := &mockURLService{
-		shortenFn: func(_ context.Context, _ string) (*service.ShortenResult, error) {
+		shortenFn: func(_ context.Context, _ service.ShortenParams) (*service.ShortenResult, error) {
 			return &service.ShortenResult{ShortURL: "http://localhost:8080/abc123"}, nil
 		},
 	}
@@ -135,7 +223,7 @@ func TestShorten_MissingURL(t *testing.T) {
 
 func TestShorten_ServiceError(t *testing.T) {
 	mock := &mockURLService{
-		shortenFn: func(_ context.Context, _ string) (*service.ShortenResult, error) {
+		shortenFn: func(_ context.Context, _ service.ShortenParams) (*service.ShortenResult, error) {
 			return nil, errors.New("бд недоступна")
 		},
 	}
@@ -153,20 +241,132 @@ func TestShorten_ServiceError(t *testing.T) {
 	}
 }
 
+func TestShorten_AliasPassedToService(t *testing.T) {
+	var got service.ShortenParams
+	mock := &mockURLService{
+		shortenFn: func(_ context.Context, params service.ShortenParams) (*service.ShortenResult, error) {
+			got = params
+			return &service.ShortenResult{ShortURL: "http://localhost:8080/spring-sale"}, nil
+		},
+	}
+	h := handler.NewShortenHandler(mock)
+
+	body := `{"long_url":"https://example.com","alias":"spring-sale"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Shorten(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusCreated)
+	}
+	if got.Alias != "spring-sale" || got.LongURL != "https://example.com" {
+		t.Errorf("параметры = %+v, ожидался alias %q", got, "spring-sale")
+	}
+}
+
+func TestShorten_TTLPassedToService(t *testing.T) {
+	var got service.ShortenParams
+	mock := &mockURLService{
+		shortenFn: func(_ context.Context, params service.ShortenParams) (*service.ShortenResult, error) {
+			got = params
+			return &service.ShortenResult{ShortURL: "http://localhost:8080/abc123"}, nil
+		},
+	}
+	h := handler.NewShortenHandler(mock)
+
+	body := `{"long_url":"https://example.com","ttl":3600}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Shorten(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusCreated)
+	}
+	if got.TTL != time.Hour {
+		t.Errorf("TTL = %v, ожидался %v", got.TTL, time.Hour)
+	}
+}
+
+func TestShorten_TTLOutOfRange(t *testing.T) {
+	called := false
+	mock := &mockURLService{
+		shortenFn: func(_ context.Context, _ service.ShortenParams) (*service.ShortenResult, error) {
+			called = true
+			return &service.ShortenResult{ShortURL: "http://localhost:8080/abc123"}, nil
+		},
+	}
+	h := handler.NewShortenHandler(mock)
+
+	body := `{"long_url":"https://example.com","ttl":9223372036854775807}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Shorten(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusBadRequest)
+	}
+	if called {
+		t.Error("сервис не должен вызываться при ttl вне допустимого диапазона")
+	}
+}
+
+func TestShorten_AliasErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"занят", service.ErrAliasTaken, http.StatusConflict},
+		{"некорректный", service.ErrInvalidAlias, http.StatusBadRequest},
+		{"некорректный_срок", service.ErrInvalidExpiry, http.StatusBadRequest},
+		{"некорректный_лимит", service.ErrInvalidMaxClicks, http.StatusBadRequest},
+		{"заблокированный_домен", fmt.Errorf("%w: evil.example", service.ErrBlockedDomain), http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				shortenFn: func(_ context.Context, _ service.ShortenParams) (*service.ShortenResult, error) {
+					return nil, tt.err
+				},
+			}
+			h := handler.NewShortenHandler(mock)
+
+			body := `{"long_url":"https://example.com","alias":"spring-sale"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			h.Shorten(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
 // --- редирект ---
 
 func TestRedirect_Success(t *testing.T) {
 	mock := &mockURLService{
-		resolveFn: func(_ context.Context, code string) (string, error) {
+		resolveFn: func(_ context.Context, code string) (*service.Resolution, error) {
 			if code == "abc123" {
-				return "https://example.com", nil
+				return &service.Resolution{URLID: 42, LongURL: "https://example.com", RedirectType: http.StatusFound}, nil
 			}
-			return "", service.ErrNotFound
+			return nil, service.ErrNotFound
 		},
 	}
-	h := handler.NewRedirectHandler(mock)
+	recorder := &mockClickRecorder{}
+	h := handler.NewRedirectHandler(mock, recorder, geoip.Nop{}, time.Hour)
 
 	req := chiRequest(http.MethodGet, "/abc123", "shortURL", "abc123")
+	req.Header.Set("Referer", "https://news.example")
 	rec := httptest.NewRecorder()
 
 	h.Redirect(rec, req)
@@ -177,33 +377,140 @@ func TestRedirect_Success(t *testing.T) {
 	if loc := rec.Header().Get("Location"); loc != "https://example.com" {
 		t.Errorf("Location = %q, ожидался %q", loc, "https://example.com")
 	}
+	if len(recorder.events) != 1 {
+		t.Fatalf("событий перехода = %d, ожидалось 1", len(recorder.events))
+	}
+	if ev := recorder.events[0]; ev.URLID != 42 || ev.ShortURL != "abc123" || ev.Referrer != "https://news.example" {
+		t.Errorf("событие = %+v", ev)
+	}
 }
 
-func TestRedirect_NotFound(t *testing.T) {
+func TestRedirect_VariantCookie(t *testing.T) {
 	mock := &mockURLService{
-		resolveFn: func(_ context.Context, _ string) (string, error) {
-			return "", service.ErrNotFound
+		resolveFn: func(_ context.Context, _ string) (*service.Resolution, error) {
+			return &service.Resolution{URLID: 7, LongURL: "https://example.com/b", RedirectType: http.StatusFound, Targeted: true, Variant: "b"}, nil
 		},
 	}
-	h := handler.NewRedirectHandler(mock)
+	recorder := &mockClickRecorder{}
+	h := handler.NewRedirectHandler(mock, recorder, fixedCountry("DE"), time.Hour)
 
-	req := chiRequest(http.MethodGet, "/nonexistent", "shortURL", "nonexistent")
+	// Первый переход: идентификатор вычисляется и сохраняется в cookie
 	rec := httptest.NewRecorder()
+	h.Redirect(rec, chiRequest(http.MethodGet, "/ab", "shortURL", "ab"))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "tinyurl_vid" || cookies[0].Value != mock.visitor.ID {
+		t.Fatalf("cookie = %+v, ожидался идентификатор посетителя %q", cookies, mock.visitor.ID)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Variant != "b" || recorder.events[0].Country != "DE" {
+		t.Errorf("события = %+v, ожидался переход из DE с вариантом b", recorder.events)
+	}
+	if mock.visitor.Country != "DE" {
+		t.Errorf("страна посетителя = %q, ожидалась DE", mock.visitor.Country)
+	}
 
+	// Повторный переход с cookie: идентификатор берётся из неё, cookie не перезаписывается
+	req := chiRequest(http.MethodGet, "/ab", "shortURL", "ab")
+	req.AddCookie(&http.Cookie{Name: "tinyurl_vid", Value: "visitor-1"})
+	rec = httptest.NewRecorder()
 	h.Redirect(rec, req)
+	if mock.visitor.ID != "visitor-1" {
+		t.Errorf("идентификатор посетителя = %q, ожидался из cookie", mock.visitor.ID)
+	}
+	if got := rec.Header().Get("Set-Cookie"); got != "" {
+		t.Errorf("Set-Cookie = %q, ожидалось отсутствие", got)
+	}
+}
 
-	if rec.Code != http.StatusNotFound {
-		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusNotFound)
+func TestRedirect_Types(t *testing.T) {
+	soon := time.Now().Add(10 * time.Minute)
+	tests := []struct {
+		name         string
+		res          service.Resolution
+		wantStatus   int
+		cacheControl string
+	}{
+		{"301 кэшируется на permanent_redirect_max_age", service.Resolution{RedirectType: http.StatusMovedPermanently}, http.StatusMovedPermanently, "public, max-age=3600"},
+		{"308 не дольше срока действия", service.Resolution{RedirectType: http.StatusPermanentRedirect, ValidUntil: &soon}, http.StatusPermanentRedirect, "public, max-age=599"},
+		{"301 с лимитом переходов не кэшируется", service.Resolution{RedirectType: http.StatusMovedPermanently, Counted: true}, http.StatusMovedPermanently, "no-store"},
+		{"307 без Cache-Control", service.Resolution{RedirectType: http.StatusTemporaryRedirect}, http.StatusTemporaryRedirect, ""},
+		{"301 по устройству только в кэше браузера", service.Resolution{RedirectType: http.StatusMovedPermanently, Targeted: true}, http.StatusMovedPermanently, "private, max-age=3600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.res
+			res.LongURL = "https://example.com"
+			mock := &mockURLService{
+				resolveFn: func(_ context.Context, _ string) (*service.Resolution, error) { return &res, nil },
+			}
+			h := handler.NewRedirectHandler(mock, &mockClickRecorder{}, geoip.Nop{}, time.Hour)
+
+			rec := httptest.NewRecorder()
+			h.Redirect(rec, chiRequest(http.MethodGet, "/abc123", "shortURL", "abc123"))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Cache-Control = %q, ожидался %q", got, tt.cacheControl)
+			}
+			if got := rec.Header().Get("Vary") != ""; got != tt.res.Targeted {
+				t.Errorf("Vary = %q при Targeted = %v", rec.Header().Get("Vary"), tt.res.Targeted)
+			}
+		})
+	}
+}
+
+func TestRedirect_NotFound(t *testing.T) {
+	for _, err := range []error{service.ErrNotFound, service.ErrNotYetActive} {
+		t.Run(err.Error(), func(t *testing.T) {
+			mock := &mockURLService{
+				resolveFn: func(_ context.Context, _ string) (*service.Resolution, error) {
+					return nil, err
+				},
+			}
+			h := handler.NewRedirectHandler(mock, &mockClickRecorder{}, geoip.Nop{}, time.Hour)
+
+			req := chiRequest(http.MethodGet, "/nonexistent", "shortURL", "nonexistent")
+			rec := httptest.NewRecorder()
+
+			h.Redirect(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
+
+func TestRedirect_Gone(t *testing.T) {
+	for _, err := range []error{service.ErrExpired, service.ErrClickLimitReached} {
+		t.Run(err.Error(), func(t *testing.T) {
+			mock := &mockURLService{
+				resolveFn: func(_ context.Context, _ string) (*service.Resolution, error) {
+					return nil, err
+				},
+			}
+			h := handler.NewRedirectHandler(mock, &mockClickRecorder{}, geoip.Nop{}, time.Hour)
+
+			req := chiRequest(http.MethodGet, "/abc123", "shortURL", "abc123")
+			rec := httptest.NewRecorder()
+
+			h.Redirect(rec, req)
+
+			if rec.Code != http.StatusGone {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusGone)
+			}
+		})
 	}
 }
 
 func TestRedirect_ServiceError(t *testing.T) {
 	mock := &mockURLService{
-		resolveFn: func(_ context.Context, _ string) (string, error) {
-			return "", errors.New("бд недоступна")
+		resolveFn: func(_ context.Context, _ string) (*service.Resolution, error) {
+			return nil, errors.New("бд недоступна")
 		},
 	}
-	h := handler.NewRedirectHandler(mock)
+	h := handler.NewRedirectHandler(mock, &mockClickRecorder{}, geoip.Nop{}, time.Hour)
 
 	req := chiRequest(http.MethodGet, "/abc123", "shortURL", "abc123")
 	rec := httptest.NewRecorder()
@@ -215,6 +522,110 @@ func TestRedirect_ServiceError(t *testing.T) {
 	}
 }
 
+func TestRedirect_PasswordRequired(t *testing.T) {
+	mock := &mockURLService{
+		resolveFn: func(_ context.Context, _ string) (*service.Resolution, error) {
+			return nil, service.ErrPasswordRequired
+		},
+	}
+	h := handler.NewRedirectHandler(mock, &mockClickRecorder{}, geoip.Nop{}, time.Hour)
+
+	req := chiRequest(http.MethodGet, "/secret", "shortURL", "secret")
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, ожидался text/html", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `action="/secret"`) {
+		t.Error("форма не отправляется на код ссылки")
+	}
+}
+
+// --- ввод пароля ---
+
+func unlockRequest(code, password string) *http.Request {
+	req := chiRequest(http.MethodPost, "/"+code, "shortURL", code)
+	req.Body = io.NopCloser(strings.NewReader("password=" + password))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestUnlock_Success(t *testing.T) {
+	mock := &mockURLService{
+		unlockFn: func(_ context.Context, _ string, password string) (*service.Resolution, error) {
+			if password == "hunter2" {
+				return &service.Resolution{
+					LongURL:           "https://example.com/docs",
+					RedirectType:      http.StatusPermanentRedirect,
+					PasswordProtected: true,
+				}, nil
+			}
+			return nil, service.ErrWrongPassword
+		},
+	}
+	h := handler.NewRedirectHandler(mock, &mockClickRecorder{}, geoip.Nop{}, time.Hour)
+
+	rec := httptest.NewRecorder()
+	h.Unlock(rec, unlockRequest("secret", "hunter2"))
+
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusSeeOther)
+	}
+	if loc := rec.Header().Get("Location"); loc != "https://example.com/docs" {
+		t.Errorf("Location = %q, ожидался %q", loc, "https://example.com/docs")
+	}
+}
+
+func TestUnlock_PreservesMethodWithoutPassword(t *testing.T) {
+	mock := &mockURLService{
+		unlockFn: func(_ context.Context, _, _ string) (*service.Resolution, error) {
+			return &service.Resolution{LongURL: "https://api.example.com/hook", RedirectType: http.StatusTemporaryRedirect}, nil
+		},
+	}
+	h := handler.NewRedirectHandler(mock, &mockClickRecorder{}, geoip.Nop{}, time.Hour)
+
+	rec := httptest.NewRecorder()
+	h.Unlock(rec, unlockRequest("hook", ""))
+
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusTemporaryRedirect)
+	}
+}
+
+func TestUnlock_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"неверный_пароль", service.ErrWrongPassword, http.StatusUnauthorized},
+		{"много_попыток", service.ErrTooManyAttempts, http.StatusTooManyRequests},
+		{"не_найдена", service.ErrNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				unlockFn: func(_ context.Context, _, _ string) (*service.Resolution, error) {
+					return nil, tt.err
+				},
+			}
+			h := handler.NewRedirectHandler(mock, &mockClickRecorder{}, geoip.Nop{}, time.Hour)
+
+			rec := httptest.NewRecorder()
+			h.Unlock(rec, unlockRequest("secret", "wrong"))
+
+			if rec.Code != tt.want {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
 // --- проверка здоровья ---
 
 func TestHealth_OK(t *testing.T) {
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tinyurl/internal/janitor"
+)
+
+// fakeExpiredRepo — хранилище с заданным числом истёкших ссылок.
+type fakeExpiredRepo struct {
+	expired int64
+	calls   int
+	err     error
+}
+
+func (f *fakeExpiredRepo) DeleteExpired(_ context.Context, _ time.Time, limit int) (int64, error) {
+	f.calls++
+	if f.err != nil {
+		return 0, f.err
+	}
+	n := min(f.expired, int64(limit))
+	f.expired -= n
+	return n, nil
+}
+
+func TestJanitorRunOnce_Batches(t *testing.T) {
+	repo := &fakeExpiredRepo{expired: 25}
+	j := janitor.New(repo, time.Minute, 10)
+
+	got := j.RunOnce(context.Background())
+
+	if got != 25 {
+		t.Errorf("удалено = %d, ожидалось 25", got)
+	}
+	if repo.calls != 3 {
+		t.Errorf("вызовов = %d, ожидалось 3", repo.calls)
+	}
+}
+
+func TestJanitorRunOnce_Error(t *testing.T) {
+	repo := &fakeExpiredRepo{err: errors.New("бд недоступна")}
+	j := janitor.New(repo, time.Minute, 10)
+
+	if got := j.RunOnce(context.Background()); got != 0 {
+		t.Errorf("удалено = %d, ожидалось 0", got)
+	}
+	if repo.calls != 1 {
+		t.Errorf("вызовов = %d, ожидался 1", repo.calls)
+	}
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"tinyurl/internal/cache"
+	"tinyurl/internal/db"
+	"tinyurl/internal/metrics"
+	"tinyurl/internal/middleware"
+	"tinyurl/internal/repository"
+	"tinyurl/internal/service"
+	"tinyurl/pkg/snowflake"
+)
+
+// scrape возвращает текст метрик из обработчика /metrics.
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMetrics_HTTPRoutePattern(t *testing.T) {
+	m := metrics.New()
+	r := chi.NewRouter()
+	r.Use(middleware.Metrics(m))
+	r.Get("/api/v1/urls/{code}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/api/v1/urls/abc", "/api/v1/urls/def", "/unknown/path"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/api/v1/urls/abc", nil))
+
+	body := scrape(t, m)
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"шаблон маршрута вместо пути", `tinyurl_http_requests_total{method="GET",route="/api/v1/urls/{code}",status="404"} 2`},
+		{"несовпавший маршрут", `tinyurl_http_requests_total{method="GET",route="unmatched",status="404"} 1`},
+		{"нестандартный метод", `tinyurl_http_requests_total{method="OTHER",route="unmatched",status="405"} 1`},
+		{"гистограмма длительности", `tinyurl_http_request_duration_seconds_count{method="GET",route="/api/v1/urls/{code}"} 2`},
+		{"метрики рантайма Go", `go_goroutines`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(body, tt.line) {
+				t.Errorf("в метриках нет строки %q", tt.line)
+			}
+		})
+	}
+	if strings.Contains(body, "/api/v1/urls/abc") {
+		t.Error("в метки попал путь запроса")
+	}
+}
+
+func TestMetrics_ServiceEvents(t *testing.T) {
+	m := metrics.New()
+	sf, err := snowflake.New(1)
+	if err != nil {
+		t.Fatalf("ошибка инициализации snowflake: %v", err)
+	}
+	urlCache := cache.NewLocal(10, time.Minute, time.Minute)
+	m.RegisterCache(urlCache.Stats)
+	svc := service.NewURLService(repository.NewMemoryStorage().URLs, sf, "http://localhost:8080", http.StatusFound, urlCache, m)
+	ctx := context.Background()
+
+	res, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com"}); err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	code := res.ShortURL[strings.LastIndex(res.ShortURL, "/")+1:]
+	if _, err := svc.Resolve(ctx, code, service.Visitor{}); err != nil {
+		t.Fatalf("ошибка разрешения: %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "missing", service.Visitor{}); err == nil {
+		t.Fatal("ожидалась ошибка для неизвестного кода")
+	}
+
+	body := scrape(t, m)
+	for _, line := range []string{
+		`tinyurl_shortens_total{result="created"} 1`,
+		`tinyurl_shortens_total{result="deduplicated"} 1`,
+		`tinyurl_snowflake_ids_total 1`,
+		`tinyurl_redirects_total 1`,
+		`tinyurl_not_found_total 1`,
+		`tinyurl_cache_requests_total{result="hit"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("в метриках нет строки %q", line)
+		}
+	}
+}
+
+func TestMetrics_DBErrors(t *testing.T) {
+	m := metrics.New()
+	database, err := db.InitSQLite(filepath.Join(t.TempDir(), "metrics.db"))
+	if err != nil {
+		t.Fatalf("ошибка открытия sqlite: %v", err)
+	}
+	sqlDB, _ := database.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := m.InstrumentDB(database); err != nil {
+		t.Fatalf("ошибка подключения метрик: %v", err)
+	}
+
+	// Схема не создана: запрос к urls завершается ошибкой БД
+	urls := repository.NewGormStorage(database).URLs
+	if _, err := urls.FindByShortURL(context.Background(), "abc"); err == nil {
+		t.Fatal("ожидалась ошибка запроса к отсутствующей таблице")
+	}
+
+	if body := scrape(t, m); !strings.Contains(body, `tinyurl_db_errors_total{operation="query"} 1`) {
+		t.Errorf("ошибка запроса не учтена:\n%s", body)
+	}
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"tinyurl/internal/db"
+	"tinyurl/internal/migrate"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	database, err := db.InitSQLite(filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("ошибка открытия sqlite: %v", err)
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("ошибка получения sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func tableExists(t *testing.T, sqlDB *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := sqlDB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n); err != nil {
+		t.Fatalf("ошибка чтения sqlite_master: %v", err)
+	}
+	return n == 1
+}
+
+func TestMigrate_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []int64
+		wantErr bool
+	}{
+		{
+			name: "сортировка по версии, посторонние файлы пропускаются",
+			files: fstest.MapFS{
+				"010_b.up.sql":   {Data: []byte("SELECT 1")},
+				"010_b.down.sql": {Data: []byte("SELECT 1")},
+				"002_a.up.sql":   {Data: []byte("SELECT 1")},
+				"002_a.down.sql": {Data: []byte("SELECT 1")},
+				"README.md":      {Data: []byte("-")},
+			},
+			want: []int64{2, 10},
+		},
+		{
+			name: "нет down",
+			files: fstest.MapFS{
+				"001_a.up.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "разные имена у одной версии",
+			files: fstest.MapFS{
+				"001_a.up.sql":   {Data: []byte("SELECT 1")},
+				"001_b.down.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := migrate.Load(tt.files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ошибка = %v, ожидалась: %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("миграций = %d, ожидалось %d", len(got), len(tt.want))
+			}
+			for i, m := range got {
+				if m.Version != tt.want[i] {
+					t.Errorf("версия[%d] = %d, ожидалась %d", i, m.Version, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMigrate_UpDown(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := openSQLite(t)
+	migrations, err := migrate.Load(fstest.MapFS{
+		"001_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY);")},
+		"001_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"002_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER PRIMARY KEY);\nCREATE INDEX idx_b ON b (id);")},
+		"002_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	})
+	if err != nil {
+		t.Fatalf("ошибка загрузки: %v", err)
+	}
+	runner := migrate.New(sqlDB, migrate.SQLite, migrations)
+
+	if n, err := runner.Up(ctx); err != nil || n != 2 {
+		t.Fatalf("Up = %d, %v; ожидалось 2", n, err)
+	}
+	if n, err := runner.Up(ctx); err != nil || n != 0 {
+		t.Fatalf("повторный Up = %d, %v; ожидалось 0", n, err)
+	}
+	if !tableExists(t, sqlDB, "a") || !tableExists(t, sqlDB, "b") {
+		t.Fatal("таблицы не созданы")
+	}
+
+	if n, err := runner.Down(ctx, 1); err != nil || n != 1 {
+		t.Fatalf("Down = %d, %v; ожидалось 1", n, err)
+	}
+	if tableExists(t, sqlDB, "b") || !tableExists(t, sqlDB, "a") {
+		t.Fatal("Down(1) должен откатить только последнюю миграцию")
+	}
+
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatalf("ошибка Status: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Fatalf("статус = %+v, ожидалась применённой только 001", statuses)
+	}
+
+	if n, err := runner.Down(ctx, 5); err != nil || n != 1 {
+		t.Fatalf("Down = %d, %v; ожидалось 1", n, err)
+	}
+	if tableExists(t, sqlDB, "a") {
+		t.Fatal("таблица a не удалена")
+	}
+}
+
+func TestMigrate_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := openSQLite(t)
+	migrations, err := migrate.Load(fstest.MapFS{
+		"001_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY);")},
+		"001_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"002_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER);\nINSERT INTO missing VALUES (1);")},
+		"002_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	})
+	if err != nil {
+		t.Fatalf("ошибка загрузки: %v", err)
+	}
+	runner := migrate.New(sqlDB, migrate.SQLite, migrations)
+
+	if _, err := runner.Up(ctx); err == nil {
+		t.Fatal("ожидалась ошибка миграции 002")
+	}
+	if tableExists(t, sqlDB, "b") {
+		t.Error("изменения неудачной миграции должны откатиться")
+	}
+
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatalf("ошибка Status: %v", err)
+	}
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("статус = %+v, ожидалась применённой только 001", statuses)
+	}
+}
+
+func TestMigrate_EmbeddedSchemas(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.InitSQLite(filepath.Join(t.TempDir(), "embedded.db"))
+	if err != nil {
+		t.Fatalf("ошибка открытия sqlite: %v", err)
+	}
+	sqlDB, _ := database.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	runner, err := db.Migrator(database, migrate.SQLite)
+	if err != nil {
+		t.Fatalf("ошибка загрузки миграций sqlite: %v", err)
+	}
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("ошибка Up: %v", err)
+	}
+	if _, err := runner.Down(ctx, 100); err != nil {
+		t.Fatalf("ошибка Down: %v", err)
+	}
+	if tableExists(t, sqlDB, "urls") {
+		t.Error("после полного отката таблица urls не должна существовать")
+	}
+
+	// Миграции PostgreSQL здесь не выполнить, но они должны загружаться парами up/down
+	if _, err := db.Migrator(database, migrate.Postgres); err != nil {
+		t.Errorf("ошибка загрузки миграций postgres: %v", err)
+	}
+}
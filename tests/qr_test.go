@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tinyurl/internal/handler"
+	"tinyurl/internal/service"
+	"tinyurl/pkg/qr"
+)
+
+func newQRHandler() *handler.URLsHandler {
+	return handler.NewURLsHandler(&mockURLService{
+		getFn: func(_ context.Context, code string) (*service.URLInfo, error) {
+			if code != "abc123" {
+				return nil, service.ErrNotFound
+			}
+			return &service.URLInfo{Code: code, ShortURL: "http://localhost:8080/abc123"}, nil
+		},
+	})
+}
+
+func TestQR(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        string
+		query       string
+		want        int
+		contentType string
+	}{
+		{"png_по_умолчанию", "abc123", "", http.StatusOK, "image/png"},
+		{"svg", "abc123", "?format=svg&size=512&margin=2&level=h", http.StatusOK, "image/svg+xml"},
+		{"неизвестный_формат", "abc123", "?format=gif", http.StatusBadRequest, ""},
+		{"слишком_большой_размер", "abc123", "?size=10000", http.StatusBadRequest, ""},
+		{"отрицательные_поля", "abc123", "?margin=-1", http.StatusBadRequest, ""},
+		{"неизвестный_уровень", "abc123", "?level=X", http.StatusBadRequest, ""},
+		{"ссылка_не_найдена", "nope", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newQRHandler().QR(rec, chiRequest(http.MethodGet, "/api/v1/urls/"+tt.code+"/qr"+tt.query, "code", tt.code))
+
+			if rec.Code != tt.want {
+				t.Fatalf("статус = %d, ожидался %d", rec.Code, tt.want)
+			}
+			if tt.contentType == "" {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Content-Type = %q, ожидался %q", ct, tt.contentType)
+			}
+			if rec.Header().Get("ETag") == "" {
+				t.Error("ожидался заголовок ETag")
+			}
+			if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+				t.Errorf("Cache-Control = %q, ожидался %q", cc, "no-cache")
+			}
+		})
+	}
+}
+
+func TestQR_NotModified(t *testing.T) {
+	h := newQRHandler()
+
+	rec := httptest.NewRecorder()
+	h.QR(rec, chiRequest(http.MethodGet, "/api/v1/urls/abc123/qr", "code", "abc123"))
+	etag := rec.Header().Get("ETag")
+
+	req := chiRequest(http.MethodGet, "/api/v1/urls/abc123/qr", "code", "abc123")
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.QR(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusNotModified)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("тело ответа 304 должно быть пустым, получено %d байт", rec.Body.Len())
+	}
+}
+
+func TestQRCode_PNGSize(t *testing.T) {
+	code, err := qr.Encode("http://localhost:8080/abc123", qr.LevelM)
+	if err != nil {
+		t.Fatalf("ошибка кодирования: %v", err)
+	}
+
+	data, err := code.PNG(256, 4)
+	if err != nil {
+		t.Fatalf("ошибка рисования png: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("некорректный png: %v", err)
+	}
+
+	b := img.Bounds()
+	if b.Dx() != b.Dy() || b.Dx() > 256 || b.Dx() < 128 {
+		t.Errorf("размер изображения = %dx%d, ожидался квадрат не больше 256", b.Dx(), b.Dy())
+	}
+
+	if svg := string(code.SVG(256, 4)); !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, `width="256"`) {
+		t.Errorf("некорректный svg: %.80s", svg)
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tinyurl/internal/middleware"
+	"tinyurl/internal/ratelimit"
+)
+
+func TestLimiter_Refill(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := ratelimit.NewWithClock(ratelimit.Policy{Requests: 60, Period: time.Minute, Burst: 2}, func() time.Time { return now })
+
+	for i := range 2 {
+		if res := l.Allow("a"); !res.Allowed {
+			t.Fatalf("запрос %d отклонён в пределах burst", i+1)
+		}
+	}
+
+	res := l.Allow("a")
+	if res.Allowed {
+		t.Fatal("запрос сверх burst должен быть отклонён")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, ожидалось 1s", res.RetryAfter)
+	}
+
+	if res := l.Allow("b"); !res.Allowed {
+		t.Error("корзины разных клиентов должны быть независимы")
+	}
+
+	now = now.Add(time.Second)
+	if res := l.Allow("a"); !res.Allowed {
+		t.Error("через секунду должен появиться токен")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Policy{Requests: 1, Period: time.Minute})
+	h := middleware.RateLimit(limiter)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", nil)
+		req.RemoteAddr = "203.0.113.7:5555"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус = %d, ожидался %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, ожидалось 0", got)
+	}
+
+	rec = do()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("статус = %d, ожидался %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, ожидалось 60", got)
+	}
+	if got := rec.Header().Get("RateLimit-Policy"); got != "1;w=60;burst=1" {
+		t.Errorf("RateLimit-Policy = %q", got)
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted, err := middleware.ParseTrustedProxies([]string{"10.0.0.0/8", "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("ошибка разбора прокси: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		want   string
+	}{
+		{"без_заголовка", "10.0.0.5:1234", "", "10.0.0.5:1234"},
+		{"доверенный_прокси", "10.0.0.5:1234", "198.51.100.1", "198.51.100.1:0"},
+		{"цепочка_прокси", "127.0.0.1:1234", "198.51.100.1, 10.1.1.1", "198.51.100.1:0"},
+		{"подделка_слева", "10.0.0.5:1234", "1.2.3.4, 198.51.100.1", "198.51.100.1:0"},
+		{"недоверенный_источник", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7:1234"},
+		{"мусор_в_заголовке", "10.0.0.5:1234", "garbage", "10.0.0.5:1234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := middleware.RealIP(trusted)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			req.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, ожидался %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tinyurl/internal/cache"
+	"tinyurl/internal/config"
+	"tinyurl/internal/geoip"
+	"tinyurl/internal/metrics"
+	"tinyurl/internal/model"
+	"tinyurl/internal/repository"
+	"tinyurl/internal/router"
+)
+
+func TestRouter_ManageRoutesRequireAuth(t *testing.T) {
+	cfg := &config.Config{
+		App:  config.AppConfig{BaseURL: "http://localhost:8080", SnowflakeNode: 1, DefaultRedirectType: http.StatusFound},
+		Auth: config.AuthConfig{Required: true},
+	}
+	storage := repository.NewMemoryStorage()
+	if err := storage.URLs.Create(context.Background(), &model.URL{ID: 1, ShortURL: "abc", LongURL: "https://example.com", RedirectType: http.StatusFound}); err != nil {
+		t.Fatalf("ошибка создания ссылки: %v", err)
+	}
+	r := router.New(cfg, storage, &mockClickRecorder{}, cache.Nop{}, metrics.New(), geoip.Nop{}, nil)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"сведения", "/api/v1/urls/abc", http.StatusUnauthorized},
+		{"история", "/api/v1/urls/abc/history", http.StatusUnauthorized},
+		{"статистика", "/api/v1/urls/abc/stats", http.StatusUnauthorized},
+		{"qr_публичный", "/api/v1/urls/abc/qr", http.StatusOK},
+		{"редирект_публичный", "/abc", http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.want {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
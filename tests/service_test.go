@@ -0,0 +1,597 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tinyurl/internal/cache"
+	"tinyurl/internal/model"
+	"tinyurl/internal/repository"
+	"tinyurl/internal/service"
+	"tinyurl/pkg/snowflake"
+)
+
+func newTestService(t *testing.T, urlCache service.URLCache) (*service.URLService, *repository.Storage) {
+	t.Helper()
+	sf, err := snowflake.New(1)
+	if err != nil {
+		t.Fatalf("ошибка инициализации snowflake: %v", err)
+	}
+	storage := repository.NewMemoryStorage()
+	return service.NewURLService(storage.URLs, sf, "http://localhost:8080", http.StatusFound, urlCache, service.NopMetrics{}), storage
+}
+
+func TestService_ShortenDedup(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	first, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	second, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if first.ShortURL != second.ShortURL {
+		t.Errorf("повторное сокращение дало %s, ожидалась существующая %s", second.ShortURL, first.ShortURL)
+	}
+
+	limited := int64(1)
+	third, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", MaxClicks: &limited})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if third.ShortURL == first.ShortURL {
+		t.Error("ссылка с лимитом переходов должна создаваться заново")
+	}
+}
+
+func TestService_RedirectType(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	temporary, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if temporary.RedirectType != http.StatusFound {
+		t.Errorf("тип по умолчанию = %d, ожидался %d", temporary.RedirectType, http.StatusFound)
+	}
+
+	permanent, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", RedirectType: http.StatusMovedPermanently})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if permanent.ShortURL == temporary.ShortURL {
+		t.Error("ссылка с другим типом редиректа должна создаваться заново")
+	}
+
+	code := permanent.ShortURL[strings.LastIndex(permanent.ShortURL, "/")+1:]
+	res, err := svc.Resolve(ctx, code, service.Visitor{})
+	if err != nil {
+		t.Fatalf("ошибка разрешения: %v", err)
+	}
+	if res.RedirectType != http.StatusMovedPermanently {
+		t.Errorf("тип редиректа = %d, ожидался %d", res.RedirectType, http.StatusMovedPermanently)
+	}
+
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", RedirectType: http.StatusSeeOther}); !errors.Is(err, service.ErrInvalidRedirectType) {
+		t.Errorf("ошибка = %v, ожидалась ErrInvalidRedirectType", err)
+	}
+}
+
+func TestService_DeviceTargets(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	devices := map[string]string{
+		"ios":     "https://apps.apple.com/app/id1",
+		"android": "https://play.google.com/store/apps/details?id=app",
+	}
+	result, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", DeviceTargets: devices})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	plain, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if plain.ShortURL == result.ShortURL {
+		t.Error("ссылка с правилами выбора URL не должна переиспользоваться при дедупликации")
+	}
+	code := result.ShortURL[strings.LastIndex(result.ShortURL, "/")+1:]
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 Safari/604.1", devices["ios"]},
+		{"Android", "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36", devices["android"]},
+		{"десктоп без правила", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", "https://example.com"},
+		{"пустой User-Agent", "", "https://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := svc.Resolve(ctx, code, service.Visitor{UserAgent: tt.userAgent})
+			if err != nil {
+				t.Fatalf("ошибка разрешения: %v", err)
+			}
+			if res.LongURL != tt.want || !res.Targeted {
+				t.Errorf("Resolve = %q (targeted=%v), ожидался %q", res.LongURL, res.Targeted, tt.want)
+			}
+		})
+	}
+
+	invalid := []map[string]string{
+		{"windows": "https://example.com"},
+		{"ios": "itms-apps://apps.apple.com/app/id1"},
+		{"android": "play.google.com"},
+	}
+	for _, devices := range invalid {
+		if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", DeviceTargets: devices}); !errors.Is(err, service.ErrInvalidTargeting) {
+			t.Errorf("DeviceTargets %v: ошибка = %v, ожидалась ErrInvalidTargeting", devices, err)
+		}
+	}
+}
+
+func TestService_CountryTargets(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	result, err := svc.Shorten(ctx, service.ShortenParams{
+		LongURL:        "https://example.com",
+		DeviceTargets:  map[string]string{"ios": "https://apps.apple.com/app/id1"},
+		CountryTargets: map[string]string{"de": "https://example.de", "FR": "https://example.fr"},
+	})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if result.CountryTargets["DE"] != "https://example.de" {
+		t.Errorf("country_targets = %v, ожидались коды в верхнем регистре", result.CountryTargets)
+	}
+	code := result.ShortURL[strings.LastIndex(result.ShortURL, "/")+1:]
+
+	iphone := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 Safari/604.1"
+	tests := []struct {
+		name    string
+		visitor service.Visitor
+		want    string
+	}{
+		{"страна с правилом", service.Visitor{Country: "DE"}, "https://example.de"},
+		{"страна без правила", service.Visitor{Country: "US"}, "https://example.com"},
+		{"страна неизвестна", service.Visitor{}, "https://example.com"},
+		{"правило устройства важнее страны", service.Visitor{Country: "FR", UserAgent: iphone}, "https://apps.apple.com/app/id1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := svc.Resolve(ctx, code, tt.visitor)
+			if err != nil {
+				t.Fatalf("ошибка разрешения: %v", err)
+			}
+			if res.LongURL != tt.want {
+				t.Errorf("Resolve = %q, ожидался %q", res.LongURL, tt.want)
+			}
+		})
+	}
+
+	for _, countries := range []map[string]string{
+		{"DEU": "https://example.de"},
+		{"D1": "https://example.de"},
+		{"de": "https://example.de", "DE": "https://example.com/de"},
+	} {
+		if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", CountryTargets: countries}); !errors.Is(err, service.ErrInvalidTargeting) {
+			t.Errorf("CountryTargets %v: ошибка = %v, ожидалась ErrInvalidTargeting", countries, err)
+		}
+	}
+}
+
+func TestService_Variants(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	variants := []service.Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 3},
+		{Name: "b", URL: "https://example.com/b", Weight: 1},
+	}
+	result, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Variants: variants})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	code := result.ShortURL[strings.LastIndex(result.ShortURL, "/")+1:]
+
+	counts := make(map[string]int)
+	for i := range 2000 {
+		visitor := service.Visitor{ID: fmt.Sprintf("visitor-%d", i)}
+		first, err := svc.Resolve(ctx, code, visitor)
+		if err != nil {
+			t.Fatalf("ошибка разрешения: %v", err)
+		}
+		again, _ := svc.Resolve(ctx, code, visitor)
+		if again.Variant != first.Variant || again.LongURL != first.LongURL {
+			t.Fatalf("посетитель %s: вариант %q, затем %q — ожидался один и тот же", visitor.ID, first.Variant, again.Variant)
+		}
+		if want := "https://example.com/" + first.Variant; first.LongURL != want {
+			t.Errorf("LongURL = %q, ожидался %q", first.LongURL, want)
+		}
+		counts[first.Variant]++
+	}
+	// При весах 3:1 вариант a получает около 75% посетителей
+	if share := float64(counts["a"]) / 2000; share < 0.7 || share > 0.8 {
+		t.Errorf("доля варианта a = %.2f (%v), ожидалось около 0.75", share, counts)
+	}
+
+	invalid := [][]service.Variant{
+		{{Name: "a", URL: "https://example.com/a", Weight: 1}},
+		{{Name: "a", URL: "https://example.com/a", Weight: 1}, {Name: "a", URL: "https://example.com/b", Weight: 1}},
+		{{Name: "a", URL: "https://example.com/a", Weight: 0}, {Name: "b", URL: "https://example.com/b", Weight: 1}},
+		{{Name: "a", URL: "https://example.com/a", Weight: 1}, {Name: "b", URL: "ftp://example.com/b", Weight: 1}},
+	}
+	for _, variants := range invalid {
+		if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Variants: variants}); !errors.Is(err, service.ErrInvalidTargeting) {
+			t.Errorf("Variants %+v: ошибка = %v, ожидалась ErrInvalidTargeting", variants, err)
+		}
+	}
+}
+
+func TestService_Schedule(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.SetClock(func() time.Time { return now })
+	at := func(h int) *time.Time {
+		v := now.Add(time.Duration(h) * time.Hour)
+		return &v
+	}
+
+	result, err := svc.Shorten(ctx, service.ShortenParams{
+		LongURL:   "https://example.com",
+		NotBefore: at(1),
+		NotAfter:  at(10),
+		Schedule: []service.ScheduleWindow{
+			{From: at(2), To: at(4), URL: "https://example.com/sale"},
+			{From: at(6), URL: "https://example.com/after"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	code := result.ShortURL[strings.LastIndex(result.ShortURL, "/")+1:]
+
+	tests := []struct {
+		name       string
+		hour       int
+		want       string
+		validUntil int
+		err        error
+	}{
+		{"до начала окна активности", 0, "", 0, service.ErrNotYetActive},
+		{"вне окон расписания", 1, "https://example.com", 2, nil},
+		{"в окне расписания", 3, "https://example.com/sale", 4, nil},
+		{"между окнами", 5, "https://example.com", 6, nil},
+		{"окно без конца", 7, "https://example.com/after", 10, nil},
+		{"после конца окна активности", 10, "", 0, service.ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc.SetClock(func() time.Time { return *at(tt.hour) })
+			res, err := svc.Resolve(ctx, code, service.Visitor{})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ошибка = %v, ожидалась %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if res.LongURL != tt.want {
+				t.Errorf("Resolve = %q, ожидался %q", res.LongURL, tt.want)
+			}
+			if res.ValidUntil == nil || !res.ValidUntil.Equal(*at(tt.validUntil)) {
+				t.Errorf("ValidUntil = %v, ожидалось %v", res.ValidUntil, at(tt.validUntil))
+			}
+			if res.Targeted {
+				t.Error("расписание не зависит от посетителя")
+			}
+		})
+	}
+	svc.SetClock(func() time.Time { return now })
+
+	if dup, _ := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com"}); dup.ShortURL == result.ShortURL {
+		t.Error("ссылка с окном активности не должна участвовать в дедупликации")
+	}
+
+	for _, params := range []service.ShortenParams{
+		{LongURL: "https://example.com", NotAfter: at(-1)},
+		{LongURL: "https://example.com", NotBefore: at(3), NotAfter: at(2)},
+		{LongURL: "https://example.com", Schedule: []service.ScheduleWindow{{URL: "https://example.com/x"}}},
+		{LongURL: "https://example.com", Schedule: []service.ScheduleWindow{{From: at(3), To: at(2), URL: "https://example.com/x"}}},
+		{LongURL: "https://example.com", Schedule: []service.ScheduleWindow{{From: at(1), URL: "ftp://example.com"}}},
+	} {
+		if _, err := svc.Shorten(ctx, params); !errors.Is(err, service.ErrInvalidSchedule) {
+			t.Errorf("%+v: ошибка = %v, ожидалась ErrInvalidSchedule", params, err)
+		}
+	}
+}
+
+func TestService_BlockedDomains(t *testing.T) {
+	svc, storage := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	// Ссылки, созданные до блокировки домена
+	var codes []string
+	for _, params := range []service.ShortenParams{
+		{LongURL: "https://login.evil.example/account"},
+		{LongURL: "https://example.com/promo", DeviceTargets: map[string]string{"ios": "https://evil.example/app"}},
+		{LongURL: "https://example.com/safe"},
+	} {
+		result, err := svc.Shorten(ctx, params)
+		if err != nil {
+			t.Fatalf("ошибка сокращения: %v", err)
+		}
+		codes = append(codes, result.ShortURL[strings.LastIndex(result.ShortURL, "/")+1:])
+	}
+
+	list, _ := openBlocklist(t, "evil.example\nпример.рф\n")
+	svc.SetDomainPolicy(list)
+
+	for _, params := range []service.ShortenParams{
+		{LongURL: "https://evil.example"},
+		{LongURL: "https://bank.example@sub.evil.example/login"},
+		{LongURL: "https://xn--e1afmkfd.xn--p1ai/"},
+		{LongURL: "https://example.com", Variants: []service.Variant{
+			{Name: "a", URL: "https://example.com/a", Weight: 1},
+			{Name: "b", URL: "https://evil.example/b", Weight: 1},
+		}},
+	} {
+		if _, err := svc.Shorten(ctx, params); !errors.Is(err, service.ErrBlockedDomain) {
+			t.Errorf("%s: ошибка = %v, ожидалась ErrBlockedDomain", params.LongURL, err)
+		}
+	}
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com/ok"}); err != nil {
+		t.Errorf("ссылка на разрешённый домен: %v", err)
+	}
+	if _, err := svc.UpdateDestination(ctx, codes[2], "https://evil.example"); !errors.Is(err, service.ErrBlockedDomain) {
+		t.Errorf("UpdateDestination: ошибка = %v, ожидалась ErrBlockedDomain", err)
+	}
+
+	disabled, err := svc.DisableDomain(ctx, "EVIL.example")
+	if err != nil {
+		t.Fatalf("ошибка отключения: %v", err)
+	}
+	if len(disabled) != 2 {
+		t.Fatalf("отключены %v, ожидались 2 ссылки на домен", disabled)
+	}
+	for i, code := range codes {
+		u, _ := storage.URLs.FindByShortURL(ctx, code)
+		if want := i < 2; u.Disabled != want {
+			t.Errorf("%s: disabled = %v, ожидалось %v", u.LongURL, u.Disabled, want)
+		}
+	}
+	if _, err := svc.DisableDomain(ctx, " "); !errors.Is(err, service.ErrInvalidDomain) {
+		t.Errorf("пустой домен: ошибка = %v, ожидалась ErrInvalidDomain", err)
+	}
+}
+
+func TestService_Alias(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Alias: "promo"}); err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Alias: "promo"}); err != nil {
+		t.Errorf("повтор с тем же URL должен быть идемпотентным, получено %v", err)
+	}
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.org", Alias: "promo"}); !errors.Is(err, service.ErrAliasTaken) {
+		t.Errorf("ошибка = %v, ожидалась ErrAliasTaken", err)
+	}
+	for _, alias := range []string{"api", "Metrics", "swagger"} {
+		if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.org", Alias: alias}); !errors.Is(err, service.ErrInvalidAlias) {
+			t.Errorf("%s: ошибка = %v, ожидалась ErrInvalidAlias для зарезервированного кода", alias, err)
+		}
+	}
+}
+
+func TestService_ResolveLimits(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	once := int64(1)
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Alias: "once", MaxClicks: &once}); err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	res, err := svc.Resolve(ctx, "once", service.Visitor{})
+	if err != nil || !res.Counted {
+		t.Fatalf("Resolve = %+v, %v, ожидался засчитанный переход", res, err)
+	}
+	if _, err := svc.Resolve(ctx, "once", service.Visitor{}); !errors.Is(err, service.ErrClickLimitReached) {
+		t.Errorf("ошибка = %v, ожидалась ErrClickLimitReached", err)
+	}
+
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Alias: "secret", Password: "s3cret"}); err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "secret", service.Visitor{}); !errors.Is(err, service.ErrPasswordRequired) {
+		t.Errorf("ошибка = %v, ожидалась ErrPasswordRequired", err)
+	}
+	if _, err := svc.Unlock(ctx, "secret", "wrong", service.Visitor{}); !errors.Is(err, service.ErrWrongPassword) {
+		t.Errorf("ошибка = %v, ожидалась ErrWrongPassword", err)
+	}
+	if res, err := svc.Unlock(ctx, "secret", "s3cret", service.Visitor{}); err != nil || res.LongURL != "https://example.com" {
+		t.Errorf("Unlock = %+v, %v, ожидался редирект", res, err)
+	}
+
+	if _, err := svc.Resolve(ctx, "missing", service.Visitor{}); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("ошибка = %v, ожидалась ErrNotFound", err)
+	}
+}
+
+func TestService_UnlockAttemptsPerClient(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Alias: "secret", Password: "s3cret"}); err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+
+	attacker := service.Visitor{IP: "203.0.113.7"}
+	for i := 0; i < 5; i++ {
+		if _, err := svc.Unlock(ctx, "secret", "wrong", attacker); !errors.Is(err, service.ErrWrongPassword) {
+			t.Fatalf("попытка %d: ошибка = %v, ожидалась ErrWrongPassword", i+1, err)
+		}
+	}
+	if _, err := svc.Unlock(ctx, "secret", "s3cret", attacker); !errors.Is(err, service.ErrTooManyAttempts) {
+		t.Errorf("ошибка = %v, ожидалась ErrTooManyAttempts для исчерпавшего попытки клиента", err)
+	}
+
+	owner := service.Visitor{IP: "198.51.100.1"}
+	if res, err := svc.Unlock(ctx, "secret", "s3cret", owner); err != nil || res.LongURL != "https://example.com" {
+		t.Errorf("Unlock = %+v, %v, перебор с другого адреса не должен блокировать владельца пароля", res, err)
+	}
+}
+
+func TestService_UpdateHistoryRollback(t *testing.T) {
+	svc, _ := newTestService(t, cache.Nop{})
+	ctx := service.WithActor(context.Background(), "marketing")
+
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com/v1", Alias: "page"}); err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if _, err := svc.UpdateDestination(ctx, "page", "https://example.com/v2"); err != nil {
+		t.Fatalf("ошибка изменения: %v", err)
+	}
+	info, err := svc.Rollback(ctx, "page", 1)
+	if err != nil {
+		t.Fatalf("ошибка отката: %v", err)
+	}
+	if info.LongURL != "https://example.com/v1" {
+		t.Errorf("после отката URL = %s, ожидался v1", info.LongURL)
+	}
+
+	history, err := svc.History(ctx, "page")
+	if err != nil {
+		t.Fatalf("ошибка получения истории: %v", err)
+	}
+	if len(history) != 2 || history[1].Version != 2 || history[1].ChangedBy != "marketing" {
+		t.Errorf("история = %+v, ожидались 2 версии от marketing", history)
+	}
+
+	if _, err := svc.Rollback(ctx, "page", 9); !errors.Is(err, service.ErrVersionNotFound) {
+		t.Errorf("ошибка = %v, ожидалась ErrVersionNotFound", err)
+	}
+}
+
+func TestService_CacheInvalidation(t *testing.T) {
+	svc, _ := newTestService(t, cache.NewLocal(100, time.Hour, time.Hour))
+	ctx := context.Background()
+
+	// Отсутствие кода кэшируется, но создание ссылки с этим кодом его перекрывает
+	if _, err := svc.Resolve(ctx, "later", service.Visitor{}); !errors.Is(err, service.ErrNotFound) {
+		t.Fatalf("ошибка = %v, ожидалась ErrNotFound", err)
+	}
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Alias: "later"}); err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "later", service.Visitor{}); err != nil {
+		t.Fatalf("после создания ссылка должна находиться, получено %v", err)
+	}
+
+	if _, err := svc.UpdateDestination(ctx, "later", "https://example.org"); err != nil {
+		t.Fatalf("ошибка изменения: %v", err)
+	}
+	if res, _ := svc.Resolve(ctx, "later", service.Visitor{}); res == nil || res.LongURL != "https://example.org" {
+		t.Errorf("Resolve = %+v, ожидался новый URL после изменения", res)
+	}
+
+	if _, err := svc.SetDisabled(ctx, "later", true); err != nil {
+		t.Fatalf("ошибка отключения: %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "later", service.Visitor{}); !errors.Is(err, service.ErrDisabled) {
+		t.Errorf("ошибка = %v, ожидалась ErrDisabled", err)
+	}
+
+	if err := svc.Delete(ctx, "later"); err != nil {
+		t.Fatalf("ошибка удаления: %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "later", service.Visitor{}); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("ошибка = %v, ожидалась ErrNotFound", err)
+	}
+}
+
+func TestMemoryStorage_ConcurrentConsumeClick(t *testing.T) {
+	svc, storage := newTestService(t, cache.Nop{})
+	ctx := context.Background()
+
+	limit := int64(10)
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com", Alias: "hot", MaxClicks: &limit}); err != nil {
+		t.Fatalf("ошибка сокращения: %v", err)
+	}
+
+	results := make(chan error, 50)
+	for range 50 {
+		go func() {
+			_, err := svc.Resolve(ctx, "hot", service.Visitor{})
+			results <- err
+		}()
+	}
+
+	var ok int
+	for range 50 {
+		if err := <-results; err == nil {
+			ok++
+		}
+	}
+	if ok != int(limit) {
+		t.Errorf("успешных переходов = %d, ожидалось %d", ok, limit)
+	}
+
+	url, _ := storage.URLs.FindByShortURL(ctx, "hot")
+	if url.ClickCount != limit {
+		t.Errorf("click_count = %d, ожидалось %d", url.ClickCount, limit)
+	}
+}
+
+// takenCodeStore отвечает ErrDuplicate на первые вставки, как если бы сгенерированный
+// код уже был занят пользовательским.
+type takenCodeStore struct {
+	repository.URLStore
+	taken int
+}
+
+func (s *takenCodeStore) Create(ctx context.Context, url *model.URL) error {
+	if s.taken > 0 {
+		s.taken--
+		return repository.ErrDuplicate
+	}
+	return s.URLStore.Create(ctx, url)
+}
+
+func TestService_ShortenRetriesTakenCode(t *testing.T) {
+	sf, err := snowflake.New(1)
+	if err != nil {
+		t.Fatalf("ошибка инициализации snowflake: %v", err)
+	}
+	store := &takenCodeStore{URLStore: repository.NewMemoryStorage().URLs, taken: 1}
+	svc := service.NewURLService(store, sf, "http://localhost:8080", http.StatusFound, cache.Nop{}, service.NopMetrics{})
+	ctx := context.Background()
+
+	result, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("занятый код должен приводить к повтору с новым ID: %v", err)
+	}
+	code := result.ShortURL[strings.LastIndex(result.ShortURL, "/")+1:]
+	if url, _ := store.FindByShortURL(ctx, code); url == nil {
+		t.Errorf("ссылка %s не сохранена", code)
+	}
+
+	store.taken = 3
+	if _, err := svc.Shorten(ctx, service.ShortenParams{LongURL: "https://other.example.com"}); !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("ошибка = %v, ожидалась ErrDuplicate после исчерпания попыток", err)
+	}
+}
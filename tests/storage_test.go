@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tinyurl/internal/analytics"
+	"tinyurl/internal/db"
+	"tinyurl/internal/migrate"
+	"tinyurl/internal/model"
+	"tinyurl/internal/repository"
+)
+
+// storageBackends возвращает бэкенды хранилища, которые можно поднять без внешних сервисов.
+// Каждый тест получает чистое хранилище.
+func storageBackends() map[string]func(t *testing.T) *repository.Storage {
+	return map[string]func(t *testing.T) *repository.Storage{
+		"memory": func(*testing.T) *repository.Storage {
+			return repository.NewMemoryStorage()
+		},
+		"sqlite": func(t *testing.T) *repository.Storage {
+			database, err := db.InitSQLite(filepath.Join(t.TempDir(), "tinyurl.db"))
+			if err != nil {
+				t.Fatalf("ошибка открытия sqlite: %v", err)
+			}
+			t.Cleanup(func() {
+				if sqlDB, err := database.DB(); err == nil {
+					sqlDB.Close()
+				}
+			})
+			runner, err := db.Migrator(database, migrate.SQLite)
+			if err != nil {
+				t.Fatalf("ошибка загрузки миграций: %v", err)
+			}
+			if _, err := runner.Up(context.Background()); err != nil {
+				t.Fatalf("ошибка миграции: %v", err)
+			}
+			return repository.NewGormStorage(database)
+		},
+	}
+}
+
+func forEachBackend(t *testing.T, fn func(t *testing.T, s *repository.Storage)) {
+	for name, open := range storageBackends() {
+		t.Run(name, func(t *testing.T) {
+			fn(t, open(t))
+		})
+	}
+}
+
+func TestStorage_URLs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *repository.Storage) {
+		ctx := context.Background()
+		now := time.Now()
+		past := now.Add(-time.Hour)
+		limit := int64(1)
+
+		for _, u := range []*model.URL{
+			{ID: 1, ShortURL: "plain", LongURL: "https://example.com", RedirectType: http.StatusFound},
+			{ID: 2, ShortURL: "limited", LongURL: "https://example.com", RedirectType: http.StatusFound, MaxClicks: &limit},
+			{ID: 3, ShortURL: "expired", LongURL: "https://example.com", RedirectType: http.StatusFound, ExpiresAt: &past},
+			{ID: 5, ShortURL: "permanent", LongURL: "https://example.net", RedirectType: http.StatusMovedPermanently},
+			{ID: 6, ShortURL: "app", LongURL: "https://app.example", RedirectType: http.StatusFound, Targeting: &model.Targeting{
+				Devices: map[string]string{model.PlatformIOS: "https://apps.apple.com/app/id1"},
+			}},
+		} {
+			if err := s.URLs.Create(ctx, u); err != nil {
+				t.Fatalf("ошибка создания %s: %v", u.ShortURL, err)
+			}
+		}
+
+		if err := s.URLs.Create(ctx, &model.URL{ID: 4, ShortURL: "plain", LongURL: "https://x.com"}); !errors.Is(err, repository.ErrDuplicate) {
+			t.Errorf("ошибка = %v, ожидалась ErrDuplicate", err)
+		}
+
+		if u, err := s.URLs.FindByLongURL(ctx, "https://example.com", http.StatusFound); err != nil || u == nil || u.ShortURL != "plain" {
+			t.Errorf("FindByLongURL = %+v, %v, ожидалась plain", u, err)
+		}
+		if u, _ := s.URLs.FindByLongURL(ctx, "https://example.net", http.StatusFound); u != nil {
+			t.Errorf("FindByLongURL с другим типом редиректа = %+v, ожидался nil", u)
+		}
+		if u, _ := s.URLs.FindByLongURL(ctx, "https://example.net", http.StatusMovedPermanently); u == nil || u.RedirectType != http.StatusMovedPermanently {
+			t.Errorf("FindByLongURL = %+v, ожидалась permanent с типом 301", u)
+		}
+		if u, _ := s.URLs.FindByLongURL(ctx, "https://app.example", http.StatusFound); u != nil {
+			t.Errorf("ссылка с правилами выбора URL не должна участвовать в дедупликации, получено %+v", u)
+		}
+		if u, err := s.URLs.FindByShortURL(ctx, "app"); err != nil || u == nil || u.Targeting == nil ||
+			u.Targeting.Devices[model.PlatformIOS] != "https://apps.apple.com/app/id1" {
+			t.Errorf("FindByShortURL = %+v, %v, ожидались сохранённые правила", u, err)
+		}
+		if u, _ := s.URLs.FindByShortURL(ctx, "plain"); u == nil || u.Targeting != nil {
+			t.Errorf("FindByShortURL = %+v, ожидалась ссылка без правил", u)
+		}
+		if u, err := s.URLs.FindByShortURL(ctx, "missing"); err != nil || u != nil {
+			t.Errorf("FindByShortURL = %+v, %v, ожидалось (nil, nil)", u, err)
+		}
+
+		if ok, _ := s.URLs.ConsumeClick(ctx, 2); !ok {
+			t.Error("первый переход по ссылке с лимитом должен засчитываться")
+		}
+		if ok, _ := s.URLs.ConsumeClick(ctx, 2); ok {
+			t.Error("переход сверх лимита не должен засчитываться")
+		}
+
+		if found, err := s.URLs.UpdateLongURL(ctx, "plain", "https://example.org", "tester"); err != nil || !found {
+			t.Fatalf("UpdateLongURL = %v, %v", found, err)
+		}
+		if found, _ := s.URLs.UpdateLongURL(ctx, "missing", "https://example.org", "tester"); found {
+			t.Error("изменение несуществующей ссылки должно вернуть false")
+		}
+		history, err := s.URLs.FindHistory(ctx, 1)
+		if err != nil || len(history) != 1 || history[0].Version != 1 || history[0].OldLongURL != "https://example.com" {
+			t.Errorf("история = %+v, %v, ожидалась одна версия", history, err)
+		}
+		if h, _ := s.URLs.FindHistoryVersion(ctx, 1, 1); h == nil || h.ChangedBy != "tester" {
+			t.Errorf("версия 1 = %+v, ожидалось изменение от tester", h)
+		}
+
+		if found, _ := s.URLs.SetDisabled(ctx, "plain", true); !found {
+			t.Error("SetDisabled должен найти ссылку")
+		}
+		if u, _ := s.URLs.FindByLongURL(ctx, "https://example.org", http.StatusFound); u != nil {
+			t.Error("отключённая ссылка не должна участвовать в дедупликации")
+		}
+		if urls, err := s.URLs.FindEnabled(ctx, 0, 2); err != nil || len(urls) != 2 || urls[0].ID != 2 || urls[1].ID != 3 {
+			t.Errorf("FindEnabled = %+v, %v, ожидались ссылки 2 и 3 без отключённой", urls, err)
+		}
+		if urls, _ := s.URLs.FindEnabled(ctx, 3, 10); len(urls) != 2 || urls[0].ID != 5 || urls[1].Targeting == nil {
+			t.Errorf("FindEnabled после 3 = %+v, ожидались ссылки 5 и 6 с правилами", urls)
+		}
+
+		ended := &model.URL{ID: 7, ShortURL: "ended", LongURL: "https://example.com", RedirectType: http.StatusFound, NotAfter: &past}
+		if err := s.URLs.Create(ctx, ended); err != nil {
+			t.Fatalf("ошибка создания %s: %v", ended.ShortURL, err)
+		}
+		if n, err := s.URLs.DeleteExpired(ctx, now, 10); err != nil || n != 2 {
+			t.Errorf("DeleteExpired = %d, %v, ожидались 2 удалённые ссылки: истёкшая и с завершившимся окном", n, err)
+		}
+		if u, _ := s.URLs.FindByShortURL(ctx, "limited"); u == nil {
+			t.Error("ссылка без срока действия не должна удаляться")
+		}
+
+		if found, _ := s.URLs.DeleteByShortURL(ctx, "plain"); !found {
+			t.Error("удаление должно найти ссылку")
+		}
+		if history, _ := s.URLs.FindHistory(ctx, 1); len(history) != 0 {
+			t.Errorf("история удалённой ссылки = %+v, ожидалась пустая", history)
+		}
+
+		if err := s.URLs.Ping(ctx); err != nil {
+			t.Errorf("Ping: %v", err)
+		}
+	})
+}
+
+func TestStorage_ClicksAndStats(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *repository.Storage) {
+		ctx := context.Background()
+		if err := s.URLs.Create(ctx, &model.URL{ID: 1, ShortURL: "abc", LongURL: "https://example.com"}); err != nil {
+			t.Fatalf("ошибка создания: %v", err)
+		}
+
+		hour := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+		clicks := []model.Click{
+			{URLID: 1, ShortURL: "abc", ClickedAt: hour.Add(5 * time.Minute), Referrer: "https://google.com/"},
+			{URLID: 1, ShortURL: "abc", ClickedAt: hour.Add(10 * time.Minute), Referrer: "https://google.com/"},
+			{URLID: 1, ShortURL: "abc", ClickedAt: hour.Add(70 * time.Minute)},
+		}
+		if err := s.Clicks.SaveBatch(ctx, clicks, map[int64]int64{1: 3}); err != nil {
+			t.Fatalf("ошибка записи переходов: %v", err)
+		}
+		if u, _ := s.URLs.FindByShortURL(ctx, "abc"); u.ClickCount != 3 {
+			t.Errorf("click_count = %d, ожидалось 3", u.ClickCount)
+		}
+
+		// Две пачки: агрегаты должны складываться, а не перезаписываться
+		for _, limit := range []int{2, 10} {
+			if _, err := s.Stats.AggregatePending(ctx, limit, analytics.Summarize); err != nil {
+				t.Fatalf("ошибка агрегации: %v", err)
+			}
+		}
+		if n, _ := s.Stats.AggregatePending(ctx, 10, analytics.Summarize); n != 0 {
+			t.Errorf("повторная агрегация обработала %d событий, ожидалось 0", n)
+		}
+
+		hourly, err := s.Stats.FindHourly(ctx, 1, hour, hour.Add(24*time.Hour))
+		if err != nil || len(hourly) != 2 || hourly[0].Clicks != 2 || hourly[1].Clicks != 1 {
+			t.Errorf("почасовые агрегаты = %+v, %v, ожидалось 2 и 1", hourly, err)
+		}
+
+		day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		top, err := s.Stats.TopDimension(ctx, 1, model.DimensionReferrer, day, day, 10)
+		if err != nil || len(top) != 2 || top[0].Value != "google.com" || top[0].Clicks != 2 {
+			t.Errorf("топ источников = %+v, %v, ожидался google.com с 2 переходами первым", top, err)
+		}
+	})
+}
+
+func TestStorage_APIKeys(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *repository.Storage) {
+		ctx := context.Background()
+		key := &model.APIKey{Name: "marketing", Prefix: "tu_12345678", KeyHash: "hash"}
+		if err := s.APIKeys.Create(ctx, key); err != nil {
+			t.Fatalf("ошибка создания ключа: %v", err)
+		}
+
+		if k, err := s.APIKeys.FindActiveByHash(ctx, "hash"); err != nil || k == nil || k.Name != "marketing" {
+			t.Errorf("FindActiveByHash = %+v, %v", k, err)
+		}
+		if ok, _ := s.APIKeys.Revoke(ctx, key.ID, time.Now()); !ok {
+			t.Error("отзыв должен найти ключ")
+		}
+		if ok, _ := s.APIKeys.Revoke(ctx, key.ID, time.Now()); ok {
+			t.Error("повторный отзыв должен вернуть false")
+		}
+		if k, _ := s.APIKeys.FindActiveByHash(ctx, "hash"); k != nil {
+			t.Error("отозванный ключ не должен находиться")
+		}
+		if keys, _ := s.APIKeys.List(ctx); len(keys) != 1 || keys[0].RevokedAt == nil {
+			t.Errorf("список ключей = %+v, ожидался один отозванный", keys)
+		}
+	})
+}
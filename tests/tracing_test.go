@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"tinyurl/internal/cache"
+	"tinyurl/internal/db"
+	"tinyurl/internal/middleware"
+	"tinyurl/internal/migrate"
+	"tinyurl/internal/repository"
+	"tinyurl/internal/service"
+	"tinyurl/internal/tracing"
+	"tinyurl/pkg/snowflake"
+)
+
+// recordSpans подменяет глобальный провайдер трассировок на записывающий в память.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func TestTracing_RedirectSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	database, err := db.InitSQLite(filepath.Join(t.TempDir(), "tracing.db"))
+	if err != nil {
+		t.Fatalf("ошибка открытия sqlite: %v", err)
+	}
+	sqlDB, _ := database.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	runner, err := db.Migrator(database, migrate.SQLite)
+	if err != nil {
+		t.Fatalf("ошибка загрузки миграций: %v", err)
+	}
+	if _, err := runner.Up(context.Background()); err != nil {
+		t.Fatalf("ошибка миграции: %v", err)
+	}
+	if err := tracing.InstrumentDB(database, "sqlite"); err != nil {
+		t.Fatalf("ошибка подключения трассировки: %v", err)
+	}
+
+	sf, _ := snowflake.New(1)
+	svc := service.NewURLService(repository.NewGormStorage(database).URLs, sf, "http://localhost:8080", http.StatusFound, cache.Nop{}, service.NopMetrics{})
+
+	var logs bytes.Buffer
+	prevLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prevLogger) })
+
+	r := chi.NewRouter()
+	r.Use(middleware.Tracing)
+	r.Use(middleware.Logging)
+	r.Get("/{shortURL}", func(w http.ResponseWriter, r *http.Request) {
+		_, err := svc.Resolve(r.Context(), chi.URLParam(r, "shortURL"), service.Visitor{})
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+
+	server, ok := spans["GET /{shortURL}"]
+	if !ok {
+		t.Fatalf("нет серверного спана, записаны: %v", spanNames(recorder.Ended()))
+	}
+	if got := server.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("trace id = %s, ожидался из traceparent %s", got, traceID)
+	}
+
+	tests := []struct {
+		name   string
+		span   string
+		parent string
+	}{
+		{"спан сервиса внутри HTTP-запроса", "URLService.Resolve", "GET /{shortURL}"},
+		{"спан запроса GORM внутри сервиса", "gorm.query", "URLService.Resolve"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span, ok := spans[tt.span]
+			if !ok {
+				t.Fatalf("нет спана %s", tt.span)
+			}
+			if got, want := span.Parent().SpanID(), spans[tt.parent].SpanContext().SpanID(); got != want {
+				t.Errorf("родитель %s = %s, ожидался %s", tt.span, got, want)
+			}
+		})
+	}
+
+	if !strings.Contains(logs.String(), `"trace_id":"`+traceID+`"`) {
+		t.Errorf("в логе запроса нет trace_id: %s", logs.String())
+	}
+
+	if got := spans["URLService.Resolve"].Status().Code; got != codes.Unset {
+		t.Errorf("статус спана для неизвестного кода = %v, ожидался %v", got, codes.Unset)
+	}
+
+	// Сбой хранилища, в отличие от ненайденной ссылки, помечает спан ошибкой.
+	sqlDB.Close()
+	if _, err := svc.Resolve(context.Background(), "abc", service.Visitor{}); err == nil {
+		t.Fatal("ожидалась ошибка закрытой базы")
+	}
+	ended := recorder.Ended()
+	if got := ended[len(ended)-1]; got.Name() != "URLService.Resolve" || got.Status().Code != codes.Error {
+		t.Errorf("спан %s со статусом %v, ожидался URLService.Resolve со статусом %v", got.Name(), got.Status().Code, codes.Error)
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, 0, len(spans))
+	for _, s := range spans {
+		names = append(names, s.Name())
+	}
+	return names
+}
@@ -0,0 +1,274 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tinyurl/internal/dto"
+	"tinyurl/internal/handler"
+	"tinyurl/internal/middleware"
+	"tinyurl/internal/service"
+)
+
+func TestURLsGet_Success(t *testing.T) {
+	mock := &mockURLService{
+		getFn: func(_ context.Context, code string) (*service.URLInfo, error) {
+			return &service.URLInfo{Code: code, LongURL: "https://example.com", ClickCount: 7}, nil
+		},
+	}
+	h := handler.NewURLsHandler(mock)
+
+	rec := httptest.NewRecorder()
+	h.Get(rec, chiRequest(http.MethodGet, "/api/v1/urls/abc123", "code", "abc123"))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusOK)
+	}
+
+	var resp dto.URLResponse
+	decodeJSON(t, rec, &resp)
+	if resp.Code != "abc123" || resp.ClickCount != 7 {
+		t.Errorf("ответ = %+v, ожидался код abc123 и 7 переходов", resp)
+	}
+}
+
+func TestURLsGet_PasswordProtected(t *testing.T) {
+	mock := &mockURLService{
+		getFn: func(_ context.Context, code string) (*service.URLInfo, error) {
+			return &service.URLInfo{
+				Code:              code,
+				LongURL:           "https://example.com/internal",
+				PasswordProtected: true,
+				CountryTargets:    map[string]string{"DE": "https://example.de/internal"},
+			}, nil
+		},
+	}
+	h := handler.NewURLsHandler(mock)
+	authn := &mockAuthenticator{keys: map[string]string{"tu_good": "marketing"}}
+	get := middleware.Auth(authn, false)(http.HandlerFunc(h.Get))
+
+	tests := []struct {
+		name     string
+		header   string
+		wantLong string
+	}{
+		{"анонимно", "", ""},
+		{"с_ключом", "Bearer tu_good", "https://example.com/internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := chiRequest(http.MethodGet, "/api/v1/urls/secret", "code", "secret")
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			get.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("статус = %d, ожидался %d", rec.Code, http.StatusOK)
+			}
+			var resp dto.URLResponse
+			decodeJSON(t, rec, &resp)
+			if resp.LongURL != tt.wantLong || !resp.PasswordProtected {
+				t.Errorf("ответ = %+v, ожидался long_url %q", resp, tt.wantLong)
+			}
+			if tt.wantLong == "" && resp.CountryTargets != nil {
+				t.Errorf("правила выбора URL = %v, анонимному запросу они не должны раскрываться", resp.CountryTargets)
+			}
+		})
+	}
+}
+
+func TestURLsGet_NotFound(t *testing.T) {
+	mock := &mockURLService{
+		getFn: func(_ context.Context, _ string) (*service.URLInfo, error) {
+			return nil, service.ErrNotFound
+		},
+	}
+	h := handler.NewURLsHandler(mock)
+
+	rec := httptest.NewRecorder()
+	h.Get(rec, chiRequest(http.MethodGet, "/api/v1/urls/nope", "code", "nope"))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestURLsUpdate(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"успех", `{"long_url":"https://example.org"}`, http.StatusOK},
+		{"некорректный_url", `{"long_url":"not-a-url"}`, http.StatusBadRequest},
+		{"некорректный_json", `{invalid`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				updateFn: func(_ context.Context, code, longURL string) (*service.URLInfo, error) {
+					return &service.URLInfo{Code: code, LongURL: longURL}, nil
+				},
+			}
+			h := handler.NewURLsHandler(mock)
+
+			req := chiRequest(http.MethodPatch, "/api/v1/urls/abc123", "code", "abc123")
+			req.Body = io.NopCloser(strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			h.Update(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLsHistory(t *testing.T) {
+	mock := &mockURLService{
+		getFn: func(_ context.Context, code string) (*service.URLInfo, error) {
+			return &service.URLInfo{Code: code, LongURL: "https://b.example"}, nil
+		},
+		historyFn: func(_ context.Context, _ string) ([]service.HistoryEntry, error) {
+			return []service.HistoryEntry{
+				{Version: 1, OldLongURL: "https://a.example", NewLongURL: "https://b.example", ChangedBy: "anonymous"},
+			}, nil
+		},
+	}
+	h := handler.NewURLsHandler(mock)
+
+	rec := httptest.NewRecorder()
+	h.History(rec, chiRequest(http.MethodGet, "/api/v1/urls/abc123/history", "code", "abc123"))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusOK)
+	}
+
+	var resp []dto.HistoryEntryResponse
+	decodeJSON(t, rec, &resp)
+	if len(resp) != 1 || resp[0].NewLongURL != "https://b.example" {
+		t.Errorf("история = %+v, ожидалась одна запись", resp)
+	}
+}
+
+func TestURLsHistory_PasswordProtected(t *testing.T) {
+	mock := &mockURLService{
+		getFn: func(_ context.Context, code string) (*service.URLInfo, error) {
+			return &service.URLInfo{Code: code, LongURL: "https://b.example", PasswordProtected: true}, nil
+		},
+		historyFn: func(_ context.Context, _ string) ([]service.HistoryEntry, error) {
+			return []service.HistoryEntry{
+				{Version: 1, OldLongURL: "https://a.example", NewLongURL: "https://b.example", ChangedBy: "marketing"},
+			}, nil
+		},
+	}
+	h := handler.NewURLsHandler(mock)
+	authn := &mockAuthenticator{keys: map[string]string{"tu_good": "marketing"}}
+	history := middleware.Auth(authn, false)(http.HandlerFunc(h.History))
+
+	tests := []struct {
+		name    string
+		header  string
+		wantNew string
+	}{
+		{"анонимно", "", ""},
+		{"с_ключом", "Bearer tu_good", "https://b.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := chiRequest(http.MethodGet, "/api/v1/urls/secret/history", "code", "secret")
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			history.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("статус = %d, ожидался %d", rec.Code, http.StatusOK)
+			}
+			var resp []dto.HistoryEntryResponse
+			decodeJSON(t, rec, &resp)
+			if len(resp) != 1 || resp[0].NewLongURL != tt.wantNew || resp[0].Version != 1 {
+				t.Errorf("история = %+v, ожидалась одна запись с new_long_url %q", resp, tt.wantNew)
+			}
+		})
+	}
+}
+
+func TestURLsRollback(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"успех", `{"version":1}`, nil, http.StatusOK},
+		{"нет_версии", `{"version":5}`, service.ErrVersionNotFound, http.StatusNotFound},
+		{"некорректная_версия", `{"version":0}`, nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockURLService{
+				rollbackFn: func(_ context.Context, code string, _ int) (*service.URLInfo, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &service.URLInfo{Code: code}, nil
+				},
+			}
+			h := handler.NewURLsHandler(mock)
+
+			req := chiRequest(http.MethodPost, "/api/v1/urls/abc123/rollback", "code", "abc123")
+			req.Body = io.NopCloser(strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			h.Rollback(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("статус = %d, ожидался %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLsDisable(t *testing.T) {
+	var gotDisabled bool
+	mock := &mockURLService{
+		setDisabledFn: func(_ context.Context, code string, disabled bool) (*service.URLInfo, error) {
+			gotDisabled = disabled
+			return &service.URLInfo{Code: code, Disabled: disabled}, nil
+		},
+	}
+	h := handler.NewURLsHandler(mock)
+
+	rec := httptest.NewRecorder()
+	h.Disable(rec, chiRequest(http.MethodPost, "/api/v1/urls/abc123/disable", "code", "abc123"))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusOK)
+	}
+	if !gotDisabled {
+		t.Error("ссылка не отключена")
+	}
+}
+
+func TestURLsDelete(t *testing.T) {
+	mock := &mockURLService{
+		deleteFn: func(_ context.Context, _ string) error { return nil },
+	}
+	h := handler.NewURLsHandler(mock)
+
+	rec := httptest.NewRecorder()
+	h.Delete(rec, chiRequest(http.MethodDelete, "/api/v1/urls/abc123", "code", "abc123"))
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("статус = %d, ожидался %d", rec.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"testing"
+
+	"tinyurl/pkg/useragent"
+)
+
+func TestUserAgentParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want useragent.Info
+	}{
+		{
+			"chrome_windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			useragent.Info{Browser: "Chrome", OS: "Windows", Device: "desktop"},
+		},
+		{
+			"safari_iphone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			useragent.Info{Browser: "Safari", OS: "iOS", Device: "mobile"},
+		},
+		{
+			"chrome_android",
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36",
+			useragent.Info{Browser: "Chrome", OS: "Android", Device: "mobile"},
+		},
+		{
+			"android_планшет",
+			"Mozilla/5.0 (Linux; Android 13; SM-X200) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			useragent.Info{Browser: "Chrome", OS: "Android", Device: "tablet"},
+		},
+		{
+			"firefox_macos",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.5; rv:127.0) Gecko/20100101 Firefox/127.0",
+			useragent.Info{Browser: "Firefox", OS: "macOS", Device: "desktop"},
+		},
+		{
+			"бот",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			useragent.Info{Browser: "unknown", OS: "unknown", Device: "bot"},
+		},
+		{
+			"пустой",
+			"",
+			useragent.Info{Browser: "unknown", OS: "unknown", Device: "unknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := useragent.Parse(tt.ua); got != tt.want {
+				t.Errorf("Parse() = %+v, ожидалось %+v", got, tt.want)
+			}
+		})
+	}
+}
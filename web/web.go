@@ -2,5 +2,5 @@ package web
 
 import "embed"
 
-//go:embed static/index.html
+//go:embed static/index.html static/unlock.html
 var StaticFS embed.FS